@@ -2,7 +2,6 @@ package activerecord
 
 import (
 	"errors"
-	"fmt"
 	"time"
 )
 
@@ -23,21 +22,29 @@ type ActiveRecord interface {
 // ActiveRecordModel base model with Active Record methods.
 type ActiveRecordModel struct {
 	BaseModel
+
+	// self is the model embedding this one and snapshot its attribute
+	// values, recorded when it was last loaded or saved.
+	self     interface{}
+	snapshot []interface{}
+	// associations are declared through the association methods before
+	// self is known.
+	associations Associations
 }
 
 // Create creates a new record in the database.
 func (m *ActiveRecordModel) Create() error {
-	return Create(m)
+	return Create(m.target(m))
 }
 
 // Update updates a record in the database.
 func (m *ActiveRecordModel) Update() error {
-	return Update(m)
+	return Update(m.target(m))
 }
 
 // Delete deletes a record from the database.
 func (m *ActiveRecordModel) Delete() error {
-	return Delete(m)
+	return Delete(m.target(m))
 }
 
 // Save saves a record (creates or updates).
@@ -50,7 +57,7 @@ func (m *ActiveRecordModel) Save() error {
 
 // IsNewRecord checks if a record is new.
 func (m *ActiveRecordModel) IsNewRecord() bool {
-	return m.GetID() == nil
+	return isBlankKey(m.GetID())
 }
 
 // IsPersisted checks if a record is saved in the database.
@@ -73,7 +80,17 @@ func (m *ActiveRecordModel) Reload() error {
 	if m.IsNewRecord() {
 		return nil
 	}
-	return Find(m, m.GetID())
+	return Reload(m.target(m))
+}
+
+// HardDelete deletes a record from the database, even if it is soft-deletable.
+func (m *ActiveRecordModel) HardDelete() error {
+	return HardDelete(m.target(m))
+}
+
+// Restore restores a soft-deleted record.
+func (m *ActiveRecordModel) Restore() error {
+	return Restore(m.target(m))
 }
 
 // Destroy deletes a record and returns true if successful.
@@ -92,13 +109,7 @@ func (m *ActiveRecordModel) SetUpdatedAt(t time.Time) { m.BaseModel.SetUpdatedAt
 
 // Find fills the receiver by id.
 func (m *ActiveRecordModel) Find(id interface{}) error {
-	return Find(m, id)
-}
-
-// Where returns a slice of the receiver's type matching the query.
-func (m *ActiveRecordModel) Where(query string, args ...interface{}) (interface{}, error) {
-	// This would need to be implemented based on the specific model type
-	return []interface{}{nil}, fmt.Errorf("Where method not implemented for generic ActiveRecordModel")
+	return Find(m.target(m), id)
 }
 
 var ErrNotModeler = errors.New("receiver does not implement Modeler")
@@ -1,7 +1,6 @@
 package activerecord
 
 import (
-	"reflect"
 	"testing"
 )
 
@@ -88,7 +87,7 @@ func TestActiveRecordModel_Touch_Reload_Destroy(t *testing.T) {
 	}
 }
 
-func TestActiveRecordModel_Find_Where(t *testing.T) {
+func TestActiveRecordModel_Find(t *testing.T) {
 	db, _ := Connect("sqlite3", ":memory:")
 	SetConnection(db, "sqlite3")
 	if _, err := db.Exec(`CREATE TABLE dummy (
@@ -109,14 +108,4 @@ func TestActiveRecordModel_Find_Where(t *testing.T) {
 	if err == nil {
 		t.Error("Find should fail for non-Modeler")
 	}
-	res, err := ar.Where("id = ?", 1)
-	if err == nil {
-		t.Error("Where should fail for non-Modeler")
-	}
-	t.Logf("res type: %T, value: %#v", res, res)
-	if res == nil {
-		t.Error("Where should return a slice, got nil")
-	} else if reflect.ValueOf(res).Kind() != reflect.Slice {
-		t.Error("Where should return a slice")
-	}
 }
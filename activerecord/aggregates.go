@@ -0,0 +1,163 @@
+package activerecord
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Sum returns the sum of a column, or 0 when no rows match
+func (qb *QueryBuilder) Sum(column string) (float64, error) {
+	var sum sql.NullFloat64
+	err := qb.aggregateColumn("SUM", column, &sum)
+	return sum.Float64, err
+}
+
+// Avg returns the average of a column, or 0 when no rows match
+func (qb *QueryBuilder) Avg(column string) (float64, error) {
+	var avg sql.NullFloat64
+	err := qb.aggregateColumn("AVG", column, &avg)
+	return avg.Float64, err
+}
+
+// Min scans the smallest value of a column into dest. dest is set to its
+// zero value, or nil for pointers, when no rows match.
+func (qb *QueryBuilder) Min(column string, dest interface{}) error {
+	return qb.aggregateColumn("MIN", column, dest)
+}
+
+// Max scans the largest value of a column into dest. dest is set to its
+// zero value, or nil for pointers, when no rows match.
+func (qb *QueryBuilder) Max(column string, dest interface{}) error {
+	return qb.aggregateColumn("MAX", column, dest)
+}
+
+// aggregateColumn applies an aggregate function to a column.
+func (qb *QueryBuilder) aggregateColumn(function, column string, dest interface{}) error {
+	if err := checkIdentifier(column); err != nil {
+		return err
+	}
+	return qb.aggregate(function+"("+quoteIdentifier(qb.getDialect(), column)+")", dest)
+}
+
+// aggregate computes expr over the rows of the query and scans the result
+// into dest. The query is wrapped in a subquery when DISTINCT, GROUP BY,
+// LIMIT, OFFSET or set operations would change which rows expr sees.
+func (qb *QueryBuilder) aggregate(expr string, dest interface{}) error {
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("dest must be a non-nil pointer")
+	}
+	if err := qb.validate(); err != nil {
+		return err
+	}
+	// Apply the default scope before clearing ORDER BY
+	qb = qb.withDefaultScope()
+
+	d := qb.getDialect()
+	var query string
+	var args []interface{}
+	if qb.distinct || len(qb.groupBy) > 0 || len(qb.compounds) > 0 || qb.limit > 0 || qb.offset > 0 {
+		inner, innerArgs := qb.build()
+		query = Rebind(d, "SELECT "+expr+" FROM ("+inner+") AS "+quoteIdentifier(d, "aggregated"))
+		args = innerArgs
+	} else {
+		q := qb.clone()
+		q.selectFields = []Expr{Raw(expr)}
+		q.orderBy = nil
+		if cursor := q.cursorCondition(d); cursor != nil {
+			q.where = append(q.where, cursor)
+		}
+		q.keyset, q.cursor = nil, nil
+		query, args = q.Build()
+	}
+
+	if c, ok := qb.dryRun(); ok {
+		c.record(d, query, args)
+		return ErrDryRun
+	}
+
+	var value interface{}
+	if err := GetConnection().QueryRowContext(qb.ctx, query, args...).Scan(&value); err != nil {
+		return err
+	}
+	return assignValue(d, target.Elem(), value)
+}
+
+// GroupedCount counts the rows per value of column into dest, a pointer to
+// a map from the column's type to an integer type. The column is added to
+// GROUP BY when the query has no grouping.
+//
+//	var byStatus map[string]int64
+//	err := NewQueryBuilder("orders").GroupedCount("status", &byStatus)
+func (qb *QueryBuilder) GroupedCount(column string, dest interface{}) error {
+	if err := checkIdentifier(column); err != nil {
+		return err
+	}
+	q := qb.clone()
+	q.selectFields = []Expr{columnExpr{name: column}, Raw("COUNT(*)")}
+	if len(q.groupBy) == 0 {
+		q.groupBy = []Expr{columnExpr{name: column}}
+	}
+	return q.Aggregate(dest)
+}
+
+// Aggregate executes a query, typically with Select, GroupBy and Having, and
+// scans its results into dest: a pointer to a map, filled from the first
+// and second selected columns, or a pointer to a slice of structs mapped by
+// column name.
+//
+//	var totals []struct {
+//		Status string  `db:"status"`
+//		Total  float64 `db:"total"`
+//	}
+//	err := NewQueryBuilder("orders").SelectExpr(Raw("status"), Raw("SUM(amount) AS total")).
+//		GroupBy("status").Having("SUM(amount) > ?", 100).Aggregate(&totals)
+func (qb *QueryBuilder) Aggregate(dest interface{}) error {
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || (val.Elem().Kind() != reflect.Map && val.Elem().Kind() != reflect.Slice) {
+		return fmt.Errorf("dest must be a pointer to a map or a slice")
+	}
+
+	rows, err := qb.Execute()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if val.Elem().Kind() == reflect.Map {
+		return scanMap(qb.getDialect(), rows, val.Elem())
+	}
+	return scanRows(rows, dest)
+}
+
+// scanMap scans rows of key and value columns into a map.
+func scanMap(d Dialect, rows *sql.Rows, m reflect.Value) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) != 2 {
+		return fmt.Errorf("expected 2 columns to scan into a map, got %d", len(columns))
+	}
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+
+	for rows.Next() {
+		var rawKey, rawValue interface{}
+		if err := rows.Scan(&rawKey, &rawValue); err != nil {
+			return err
+		}
+		key := reflect.New(m.Type().Key()).Elem()
+		if err := assignValue(d, key, rawKey); err != nil {
+			return fmt.Errorf("failed to scan key of %v: %w", rawKey, err)
+		}
+		value := reflect.New(m.Type().Elem()).Elem()
+		if err := assignValue(d, value, rawValue); err != nil {
+			return fmt.Errorf("failed to scan value of %v: %w", rawKey, err)
+		}
+		m.SetMapIndex(key, value)
+	}
+	return rows.Err()
+}
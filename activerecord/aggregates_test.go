@@ -0,0 +1,79 @@
+package activerecord
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAggregates(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"Go", "Rust", "Go", "Zig"} {
+		if err := Create(&article{Title: title, Body: "b", Views: (i + 1) * 10}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	qb := NewQueryBuilder("articles")
+
+	if sum, err := qb.Sum("views"); err != nil || sum != 100 {
+		t.Errorf("unexpected sum: %v, %v", sum, err)
+	}
+	if avg, err := qb.Avg("views"); err != nil || avg != 25 {
+		t.Errorf("unexpected avg: %v, %v", avg, err)
+	}
+	var min, max int
+	if err := qb.Min("views", &min); err != nil || min != 10 {
+		t.Errorf("unexpected min: %v, %v", min, err)
+	}
+	if err := qb.Max("views", &max); err != nil || max != 40 {
+		t.Errorf("unexpected max: %v, %v", max, err)
+	}
+	var newest *time.Time
+	if err := qb.Max("created_at", &newest); err != nil || newest == nil || newest.IsZero() {
+		t.Errorf("unexpected max time: %v, %v", newest, err)
+	}
+
+	// Empty sets yield zero values
+	none := NewQueryBuilder("articles").Where("views > ?", 1000)
+	if sum, err := none.Sum("views"); err != nil || sum != 0 {
+		t.Errorf("unexpected empty sum: %v, %v", sum, err)
+	}
+	if err := none.Max("created_at", &newest); err != nil || newest != nil {
+		t.Errorf("unexpected empty max: %v, %v", newest, err)
+	}
+
+	// Count respects DISTINCT and GROUP BY and leaves the select list alone
+	distinct := NewQueryBuilder("articles").Select("title").Distinct()
+	if count, err := distinct.Count(); err != nil || count != 3 {
+		t.Errorf("unexpected distinct count: %v, %v", count, err)
+	}
+	var titles []string
+	if err := distinct.OrderBy("title", "asc").Pluck("title", &titles); err != nil || len(titles) != 3 {
+		t.Errorf("unexpected distinct titles: %v, %v", titles, err)
+	}
+	if count, err := NewQueryBuilder("articles").GroupBy("title").Count(); err != nil || count != 3 {
+		t.Errorf("unexpected group count: %v, %v", count, err)
+	}
+
+	var byTitle map[string]int
+	if err := NewQueryBuilder("articles").GroupedCount("title", &byTitle); err != nil {
+		t.Fatalf("GroupedCount failed: %v", err)
+	}
+	if !reflect.DeepEqual(byTitle, map[string]int{"Go": 2, "Rust": 1, "Zig": 1}) {
+		t.Errorf("unexpected grouped count: %v", byTitle)
+	}
+
+	var totals []struct {
+		Title string  `db:"title"`
+		Total float64 `db:"total"`
+	}
+	err := NewQueryBuilder("articles").Select("title", "SUM(views) AS total").
+		GroupBy("title").Having("SUM(views) > ?", 20).OrderBy("title", "asc").
+		Aggregate(&totals)
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(totals) != 2 || totals[0].Title != "Go" || totals[0].Total != 40 || totals[1].Title != "Zig" {
+		t.Errorf("unexpected totals: %+v", totals)
+	}
+}
@@ -0,0 +1,462 @@
+package activerecord
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// joinTable is the table linking the owners of an association to their
+// associated records, one row per link.
+type joinTable struct {
+	table string
+	// ownerColumn references the owner's primary key
+	ownerColumn string
+	// targetColumn references the associated record's primary key
+	targetColumn string
+}
+
+// joinTableOf returns the join table of an association of owner to records
+// of type target. The names a has-and-belongs-to-many association leaves
+// empty are inferred from the table names: users and roles are joined by
+// roles_users(user_id, role_id).
+func joinTableOf(name string, assoc *Association, owner, target reflect.Type) (*joinTable, error) {
+	join := &joinTable{table: assoc.Through, ownerColumn: assoc.ForeignKey, targetColumn: assoc.LocalKey}
+	switch assoc.Type {
+	case HasManyThrough:
+	case HasAndBelongsToMany:
+		ownerTable, targetTable := tableOfType(owner), tableOfType(target)
+		if join.table == "" {
+			tables := []string{ownerTable, targetTable}
+			sort.Strings(tables)
+			join.table = tables[0] + "_" + tables[1]
+		}
+		var err error
+		if join.ownerColumn == "" {
+			if join.ownerColumn, err = joinKeyColumn(ownerTable); err != nil {
+				return nil, fmt.Errorf("association %s: %w; set its foreign_key", name, err)
+			}
+		}
+		if join.targetColumn == "" {
+			if join.targetColumn, err = joinKeyColumn(targetTable); err != nil {
+				return nil, fmt.Errorf("association %s: %w; set its local_key", name, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("association %s has no join table", name)
+	}
+	for _, n := range []string{join.table, join.ownerColumn, join.targetColumn} {
+		if err := checkIdentifier(n); err != nil {
+			return nil, fmt.Errorf("association %s: %w", name, err)
+		}
+	}
+	return join, nil
+}
+
+// tableOfType returns the table of a model type, without its schema.
+func tableOfType(t reflect.Type) string {
+	modeler, ok := reflect.New(t).Interface().(Modeler)
+	if !ok {
+		return ""
+	}
+	table := modeler.TableName()
+	return table[strings.LastIndexByte(table, '.')+1:]
+}
+
+// joinKeyColumn returns the join table column referencing a table: its
+// singular name followed by _id. Table names that are not recognizable
+// plurals are rejected rather than guessed.
+func joinKeyColumn(table string) (string, error) {
+	singular, ok := singularize(table)
+	if !ok {
+		return "", fmt.Errorf("cannot infer the singular of table %q", table)
+	}
+	return singular + "_id", nil
+}
+
+// joinWrite writes the join rows of one owner's association.
+type joinWrite struct {
+	name   string
+	join   *joinTable
+	owner  interface{}
+	target *ModelSchema
+	// field is the owner's association field, invalid when it has none
+	field reflect.Value
+	// tx runs the statements of the write, when set
+	tx *Transaction
+}
+
+// newJoinWrite resolves the association of a model whose join rows are
+// written. The model must be saved.
+func newJoinWrite(model interface{}, name string) (*joinWrite, error) {
+	val := reflect.ValueOf(model)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a pointer to a struct, got %T", model)
+	}
+	val = val.Elem()
+	parent := schemaFor(val.Type())
+	adoptAssociations(model)
+	assoc, err := associationFor(val.Type(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &joinWrite{name: name}
+	elem := modelStructType(assoc.Model)
+	if rel, ok := parent.Relation(name); ok {
+		elem = rel.Elem
+		w.field, _ = fieldByIndex(val, rel.Index, true)
+	}
+	if elem == nil {
+		return nil, fmt.Errorf("association %s: model must be a pointer to a model or to a slice of models", name)
+	}
+	if w.join, err = joinTableOf(name, assoc, val.Type(), elem); err != nil {
+		return nil, err
+	}
+	w.target = schemaFor(elem)
+	if len(w.target.PrimaryKeys) != 1 {
+		return nil, fmt.Errorf("association %s: %s must have a single-column primary key", name, elem.Name())
+	}
+
+	owner, err := ownerKeyField(parent, "")
+	if err != nil {
+		return nil, fmt.Errorf("association %s: %w", name, err)
+	}
+	key, ok := fieldByIndex(val, owner.Index, false)
+	if !ok || isBlankKey(key.Interface()) {
+		return nil, fmt.Errorf("association %s: %s must be saved first", name, val.Type().Name())
+	}
+	w.owner = key.Interface()
+	return w, nil
+}
+
+// keys returns the primary keys of records, pointers to or values of the
+// associated model, without duplicates and in order, along with the
+// records as pointers.
+func (w *joinWrite) keys(records []interface{}) ([]interface{}, []reflect.Value, error) {
+	var keys []interface{}
+	var items []reflect.Value
+	seen := make(map[string]bool)
+	for _, record := range records {
+		item := reflect.ValueOf(record)
+		if !item.IsValid() {
+			return nil, nil, fmt.Errorf("association %s: cannot link nil", w.name)
+		}
+		if item.Kind() != reflect.Ptr {
+			ptr := reflect.New(item.Type())
+			ptr.Elem().Set(item)
+			item = ptr
+		}
+		if item.IsNil() || item.Type().Elem() != w.target.Type {
+			return nil, nil, fmt.Errorf("association %s: cannot link %T, want *%s", w.name, record, w.target.Type.Name())
+		}
+		key, ok := fieldByIndex(item.Elem(), w.target.PrimaryKey.Index, false)
+		if !ok || isBlankKey(key.Interface()) {
+			return nil, nil, fmt.Errorf("association %s: %s must be saved first", w.name, w.target.Type.Name())
+		}
+		s := keyString([]interface{}{key.Interface()})
+		if !seen[s] {
+			seen[s] = true
+			keys = append(keys, key.Interface())
+			items = append(items, item)
+		}
+	}
+	return keys, items, nil
+}
+
+// transaction runs fn in a transaction running the statements of the
+// write. In dry-run mode they are recorded instead.
+func (w *joinWrite) transaction(ctx context.Context, fn func() error) error {
+	if inDryRun(ctx) {
+		return fn()
+	}
+	return TransactionalWithContext(ctx, func(tx *Transaction) error {
+		w.tx = tx
+		defer func() { w.tx = nil }()
+		return fn()
+	})
+}
+
+func (w *joinWrite) exec(ctx context.Context, query string, args ...interface{}) error {
+	var err error
+	if w.tx != nil {
+		_, err = w.tx.Exec(query, args...)
+	} else {
+		_, err = ExecWithContext(ctx, query, args...)
+	}
+	return err
+}
+
+func (w *joinWrite) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if w.tx != nil {
+		return w.tx.Query(query, args...)
+	}
+	return QueryWithContext(ctx, query, args...)
+}
+
+// linked returns the keys among keys already linked to the owner. Nothing
+// is linked in dry-run mode.
+func (w *joinWrite) linked(ctx context.Context, keys []interface{}) (map[string]bool, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	d := GetDialect()
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? AND %s IN (%s)",
+		quoteIdentifier(d, w.join.targetColumn),
+		quoteTable(d, w.join.table),
+		quoteIdentifier(d, w.join.ownerColumn),
+		quoteIdentifier(d, w.join.targetColumn),
+		placeholders(len(keys)))
+	rows, err := w.query(ctx, query, append([]interface{}{w.owner}, keys...)...)
+	if errors.Is(err, ErrDryRun) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	linked := make(map[string]bool)
+	for rows.Next() {
+		var key interface{}
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		linked[keyString([]interface{}{key})] = true
+	}
+	return linked, rows.Err()
+}
+
+// link inserts a join row linking the owner to each of keys not linked
+// yet, and returns the indexes of those keys.
+func (w *joinWrite) link(ctx context.Context, keys []interface{}) ([]int, error) {
+	linked, err := w.linked(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	var added []int
+	var values []string
+	var args []interface{}
+	for i, key := range keys {
+		if linked[keyString([]interface{}{key})] {
+			continue
+		}
+		added = append(added, i)
+		values = append(values, "(?, ?)")
+		args = append(args, w.owner, key)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	d := GetDialect()
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES %s",
+		quoteTable(d, w.join.table),
+		quoteIdentifier(d, w.join.ownerColumn),
+		quoteIdentifier(d, w.join.targetColumn),
+		strings.Join(values, ", "))
+	return added, w.exec(ctx, query, args...)
+}
+
+// unlink deletes the join rows linking the owner to keys or, when except
+// is set, to any record but keys.
+func (w *joinWrite) unlink(ctx context.Context, keys []interface{}, except bool) error {
+	d := GetDialect()
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?",
+		quoteTable(d, w.join.table),
+		quoteIdentifier(d, w.join.ownerColumn))
+	if len(keys) > 0 {
+		op := "IN"
+		if except {
+			op = "NOT IN"
+		}
+		query += fmt.Sprintf(" AND %s %s (%s)", quoteIdentifier(d, w.join.targetColumn), op, placeholders(len(keys)))
+	} else if !except {
+		return nil
+	}
+	return w.exec(ctx, query, append([]interface{}{w.owner}, keys...)...)
+}
+
+// appendField appends records to the owner's association field.
+func (w *joinWrite) appendField(items []reflect.Value) {
+	if !w.field.IsValid() || !w.field.CanSet() {
+		return
+	}
+	isPtr := w.field.Type().Elem().Kind() == reflect.Ptr
+	slice := w.field
+	for _, item := range items {
+		if isPtr {
+			slice = reflect.Append(slice, item)
+		} else {
+			slice = reflect.Append(slice, item.Elem())
+		}
+	}
+	w.field.Set(slice)
+}
+
+// setField sets the owner's association field to records.
+func (w *joinWrite) setField(items []reflect.Value) {
+	if !w.field.IsValid() || !w.field.CanSet() {
+		return
+	}
+	w.field.Set(reflect.MakeSlice(w.field.Type(), 0, len(items)))
+	w.appendField(items)
+}
+
+// removeField removes the records with the given keys from the owner's
+// association field.
+func (w *joinWrite) removeField(keys []interface{}) {
+	if !w.field.IsValid() || !w.field.CanSet() {
+		return
+	}
+	removed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		removed[keyString([]interface{}{key})] = true
+	}
+	kept := reflect.MakeSlice(w.field.Type(), 0, w.field.Len())
+	for i := 0; i < w.field.Len(); i++ {
+		item := w.field.Index(i)
+		record := item
+		if record.Kind() == reflect.Ptr {
+			if record.IsNil() {
+				continue
+			}
+			record = record.Elem()
+		}
+		key, ok := fieldByIndex(record, w.target.PrimaryKey.Index, false)
+		if ok && removed[keyString([]interface{}{key.Interface()})] {
+			continue
+		}
+		kept = reflect.Append(kept, item)
+	}
+	w.field.Set(kept)
+}
+
+// AppendAssociationWithContext links saved records to a saved model through
+// the join table of its association, skipping records already linked, and
+// appends the newly linked records to the association field. The join rows
+// are written in a transaction.
+//
+//	err := AppendAssociationWithContext(ctx, &post, "Tags", &golang, &databases)
+func AppendAssociationWithContext(ctx context.Context, model interface{}, name string, records ...interface{}) error {
+	w, err := newJoinWrite(model, name)
+	if err != nil {
+		return err
+	}
+	keys, items, err := w.keys(records)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	var added []int
+	err = w.transaction(ctx, func() error {
+		added, err = w.link(ctx, keys)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append to %s: %w", name, err)
+	}
+	newItems := make([]reflect.Value, len(added))
+	for i, index := range added {
+		newItems[i] = items[index]
+	}
+	w.appendField(newItems)
+	return nil
+}
+
+// RemoveAssociationWithContext unlinks records from a saved model by
+// deleting their rows in the join table of its association, and removes
+// them from the association field. The records themselves are kept.
+func RemoveAssociationWithContext(ctx context.Context, model interface{}, name string, records ...interface{}) error {
+	w, err := newJoinWrite(model, name)
+	if err != nil {
+		return err
+	}
+	keys, _, err := w.keys(records)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	err = w.transaction(ctx, func() error {
+		return w.unlink(ctx, keys, false)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove from %s: %w", name, err)
+	}
+	w.removeField(keys)
+	return nil
+}
+
+// ReplaceAssociationWithContext links a saved model to exactly the given
+// saved records, deleting and inserting its join rows in a transaction, and
+// sets the association field to them.
+func ReplaceAssociationWithContext(ctx context.Context, model interface{}, name string, records ...interface{}) error {
+	w, err := newJoinWrite(model, name)
+	if err != nil {
+		return err
+	}
+	keys, items, err := w.keys(records)
+	if err != nil {
+		return err
+	}
+	err = w.transaction(ctx, func() error {
+		if err := w.unlink(ctx, keys, true); err != nil {
+			return err
+		}
+		_, err := w.link(ctx, keys)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+	w.setField(items)
+	return nil
+}
+
+// ClearAssociationWithContext unlinks all records from a saved model by
+// deleting its rows in the join table of its association, and empties the
+// association field. The records themselves are kept.
+func ClearAssociationWithContext(ctx context.Context, model interface{}, name string) error {
+	return ReplaceAssociationWithContext(ctx, model, name)
+}
+
+// Append links records to an association through its join table; see
+// AppendAssociationWithContext.
+func (m *ActiveRecordModel) Append(name string, records ...interface{}) error {
+	target := m.target(nil)
+	if target == nil {
+		return ErrUnknownModel
+	}
+	return AppendAssociationWithContext(context.Background(), target, name, records...)
+}
+
+// Remove unlinks records from an association; see
+// RemoveAssociationWithContext.
+func (m *ActiveRecordModel) Remove(name string, records ...interface{}) error {
+	target := m.target(nil)
+	if target == nil {
+		return ErrUnknownModel
+	}
+	return RemoveAssociationWithContext(context.Background(), target, name, records...)
+}
+
+// Replace links an association to exactly the given records; see
+// ReplaceAssociationWithContext.
+func (m *ActiveRecordModel) Replace(name string, records ...interface{}) error {
+	target := m.target(nil)
+	if target == nil {
+		return ErrUnknownModel
+	}
+	return ReplaceAssociationWithContext(context.Background(), target, name, records...)
+}
+
+// Clear unlinks all records from an association; see
+// ClearAssociationWithContext.
+func (m *ActiveRecordModel) Clear(name string) error {
+	target := m.target(nil)
+	if target == nil {
+		return ErrUnknownModel
+	}
+	return ClearAssociationWithContext(context.Background(), target, name)
+}
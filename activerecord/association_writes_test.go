@@ -0,0 +1,367 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadHasOneAndHasManyThrough(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var ann, bob plAuthor
+	if err := Find(&ann, 1); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := Find(&bob, 2); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := ann.Load("Profile"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if ann.Profile == nil || ann.Profile.Bio != "writer" {
+		t.Errorf("unexpected profile: %+v", ann.Profile)
+	}
+	if err := bob.Load("Profile"); err != nil || bob.Profile != nil {
+		t.Errorf("expected no profile for bob, got %+v, %v", bob.Profile, err)
+	}
+	if err := ann.Load("LatestPost"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if ann.LatestPost == nil || ann.LatestPost.Title != "a2" {
+		t.Errorf("expected the latest post a2, got %+v", ann.LatestPost)
+	}
+
+	var post plPost
+	if err := Find(&post, 1); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := post.Load("Tags"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(post.Tags) != 2 || post.Tags[0].Name != "go" || post.Tags[1].Name != "sql" {
+		t.Errorf("unexpected tags: %+v", post.Tags)
+	}
+}
+
+func TestLoadHasManyAndBelongsTo(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var ann plAuthor
+	if err := Find(&ann, 1); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := ann.Load("Posts"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(ann.Posts) != 2 || ann.Posts[0].Title != "a1" || ann.Posts[1].Title != "a2" {
+		t.Errorf("unexpected posts: %+v", ann.Posts)
+	}
+	var post plPost
+	if err := Find(&post, 2); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := post.Load("Author"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if post.Author == nil || post.Author.Name != "bob" {
+		t.Errorf("unexpected author: %+v", post.Author)
+	}
+
+	// without a field, the association loads into its model by its local key
+	var posts []*plPost
+	RegisterAssociation(&plComment{}, "AuthorPosts", &Association{
+		Type: HasMany, Model: &posts, ForeignKey: "author_id", LocalKey: "AuthorID",
+	})
+	var comment plComment
+	if err := Find(&comment, 3); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := comment.Load("AuthorPosts"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(posts) != 2 || posts[0].Title != "a1" {
+		t.Errorf("expected the posts of ann, got %+v", posts)
+	}
+
+	if err := (&plAuthor{}).Load("Posts"); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("expected ErrUnknownModel for a model never loaded, got %v", err)
+	}
+}
+
+func TestAppendRemoveAssociation(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var post plPost
+	var golang, sql plTag
+	if err := Find(&post, 2); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := Find(&golang, 1); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := Find(&sql, 2); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := post.Load("Tags"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	taggings := func() int {
+		var n int
+		if err := QueryRow("SELECT COUNT(*) FROM pl_taggings WHERE post_id = 2").Scan(&n); err != nil {
+			t.Fatalf("count failed: %v", err)
+		}
+		return n
+	}
+
+	// sql is already linked, so only go is inserted
+	if err := post.Append("Tags", &golang, &sql, golang); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if n := taggings(); n != 2 {
+		t.Errorf("expected 2 join rows, got %d", n)
+	}
+	if len(post.Tags) != 2 || post.Tags[0].Name != "sql" || post.Tags[1].Name != "go" {
+		t.Errorf("unexpected tags after Append: %+v", post.Tags)
+	}
+
+	if err := post.Remove("Tags", &sql); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if n := taggings(); n != 1 {
+		t.Errorf("expected 1 join row, got %d", n)
+	}
+	if len(post.Tags) != 1 || post.Tags[0].Name != "go" {
+		t.Errorf("unexpected tags after Remove: %+v", post.Tags)
+	}
+	var reloaded plPost
+	if err := Find(&reloaded, 2); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := reloaded.Load("Tags"); err != nil || len(reloaded.Tags) != 1 || reloaded.Tags[0].Name != "go" {
+		t.Errorf("unexpected reloaded tags: %+v, %v", reloaded.Tags, err)
+	}
+
+	if err := post.Append("Tags", &plTag{Name: "new"}); err == nil || !strings.Contains(err.Error(), "saved") {
+		t.Errorf("expected an error for an unsaved tag, got %v", err)
+	}
+	if err := post.Append("Tags", &plComment{}); err == nil {
+		t.Error("expected an error for a record of the wrong type")
+	}
+	if err := post.Append("Comments", &plComment{}); err == nil || !strings.Contains(err.Error(), "no join table") {
+		t.Errorf("expected an error for an association without a join table, got %v", err)
+	}
+	if err := post.Append("Tags", nil); err == nil {
+		t.Error("expected an error for a nil record")
+	}
+}
+
+func TestAppendAssociationDryRun(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var post plPost
+	if err := Find(&post, 2); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	golang := plTag{}
+	golang.ID = 1
+
+	c := NewDryRunCollector()
+	if err := AppendAssociationWithContext(DryRun(context.Background(), c), &post, "Tags", &golang); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	queries := c.SQL()
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 statements, got %q", queries)
+	}
+	want := `INSERT INTO "pl_taggings" ("post_id", "tag_id") VALUES (2, 1)`
+	if queries[1] != want {
+		t.Errorf("expected %s, got %s", want, queries[1])
+	}
+	var n int
+	if err := QueryRow("SELECT COUNT(*) FROM pl_taggings WHERE post_id = 2").Scan(&n); err != nil || n != 1 {
+		t.Errorf("expected the join rows unchanged, got %d, %v", n, err)
+	}
+}
+
+type hbUser struct {
+	ActiveRecordModel
+	Name  string    `db:"name"`
+	Roles []*hbRole `ar:"has_and_belongs_to_many"`
+}
+
+func (u *hbUser) TableName() string { return "users" }
+
+type hbRole struct {
+	ActiveRecordModel
+	Name  string   `db:"name"`
+	Users []hbUser `ar:"has_and_belongs_to_many"`
+}
+
+func (r *hbRole) TableName() string { return "roles" }
+
+func setupManyToManyTestDB(t *testing.T) (ann, bob *hbUser, admin, dev, ops *hbRole) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	for _, stmt := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at TIMESTAMP, updated_at TIMESTAMP)`,
+		`CREATE TABLE roles (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at TIMESTAMP, updated_at TIMESTAMP)`,
+		// ops (id 3) cannot be granted, to make writes fail
+		`CREATE TABLE roles_users (user_id INTEGER, role_id INTEGER CHECK (role_id <> 3))`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+	ann, bob = &hbUser{Name: "ann"}, &hbUser{Name: "bob"}
+	admin, dev, ops = &hbRole{Name: "admin"}, &hbRole{Name: "dev"}, &hbRole{Name: "ops"}
+	for _, m := range []interface{}{ann, bob, admin, dev, ops} {
+		if err := Create(m); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return ann, bob, admin, dev, ops
+}
+
+func roleNames(roles []*hbRole) string {
+	names := make([]string, len(roles))
+	for i, r := range roles {
+		names[i] = r.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestHasAndBelongsToMany(t *testing.T) {
+	ann, bob, admin, dev, ops := setupManyToManyTestDB(t)
+
+	if err := ann.Append("Roles", admin, dev); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := bob.Replace("Roles", dev); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if roleNames(ann.Roles) != "admin,dev" || roleNames(bob.Roles) != "dev" {
+		t.Errorf("unexpected roles: %s; %s", roleNames(ann.Roles), roleNames(bob.Roles))
+	}
+
+	var users []*hbUser
+	if err := NewQueryBuilder("users").OrderBy("id", "ASC").Preload("Roles").Find(&users); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(users) != 2 || roleNames(users[0].Roles) != "admin,dev" || roleNames(users[1].Roles) != "dev" {
+		t.Errorf("unexpected preloaded roles: %+v", users)
+	}
+	var role hbRole
+	if err := Find(&role, dev.ID); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := role.Load("Users"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(role.Users) != 2 || role.Users[0].Name != "ann" || role.Users[1].Name != "bob" {
+		t.Errorf("unexpected users of dev: %+v", role.Users)
+	}
+
+	if err := ann.Remove("Roles", dev); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if roleNames(ann.Roles) != "admin" {
+		t.Errorf("unexpected roles after Remove: %s", roleNames(ann.Roles))
+	}
+	if err := ann.Clear("Roles"); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if ann.Roles == nil || len(ann.Roles) != 0 {
+		t.Errorf("expected no roles after Clear, got %#v", ann.Roles)
+	}
+	var reloaded hbUser
+	if err := Find(&reloaded, bob.ID); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := reloaded.Load("Roles"); err != nil || roleNames(reloaded.Roles) != "dev" {
+		t.Errorf("expected bob to keep dev, got %s, %v", roleNames(reloaded.Roles), err)
+	}
+	var n int
+	if err := QueryRow("SELECT COUNT(*) FROM roles_users WHERE user_id = ?", ann.ID).Scan(&n); err != nil || n != 0 {
+		t.Errorf("expected no join rows for ann, got %d, %v", n, err)
+	}
+
+	// Replace deletes dev before failing to insert ops, and is rolled back
+	if err := bob.Replace("Roles", admin, ops); err == nil {
+		t.Fatal("expected Replace to fail")
+	}
+	if roleNames(bob.Roles) != "dev" {
+		t.Errorf("expected the roles field unchanged, got %s", roleNames(bob.Roles))
+	}
+	if err := reloaded.Load("Roles"); err != nil || roleNames(reloaded.Roles) != "dev" {
+		t.Errorf("expected the join rows rolled back, got %s, %v", roleNames(reloaded.Roles), err)
+	}
+}
+
+func TestHasAndBelongsToManyJoinTable(t *testing.T) {
+	userType, roleType := reflect.TypeOf(hbUser{}), reflect.TypeOf(hbRole{})
+	a, err := AssociationOf(&hbUser{}, "Roles")
+	if err != nil || a.Type != HasAndBelongsToMany {
+		t.Fatalf("unexpected association: %+v, %v", a, err)
+	}
+	join, err := joinTableOf("Roles", a, userType, roleType)
+	if err != nil || *join != (joinTable{table: "roles_users", ownerColumn: "user_id", targetColumn: "role_id"}) {
+		t.Errorf("unexpected inferred join table: %+v, %v", join, err)
+	}
+	a, _ = AssociationOf(&hbRole{}, "Users")
+	join, err = joinTableOf("Users", a, roleType, userType)
+	if err != nil || *join != (joinTable{table: "roles_users", ownerColumn: "role_id", targetColumn: "user_id"}) {
+		t.Errorf("unexpected inferred join table: %+v, %v", join, err)
+	}
+
+	explicit := &Association{Type: HasAndBelongsToMany, Through: "memberships", ForeignKey: "member_id"}
+	join, err = joinTableOf("Roles", explicit, userType, roleType)
+	if err != nil || *join != (joinTable{table: "memberships", ownerColumn: "member_id", targetColumn: "role_id"}) {
+		t.Errorf("unexpected join table: %+v, %v", join, err)
+	}
+	explicit.Through = "bad table"
+	if _, err := joinTableOf("Roles", explicit, userType, roleType); err == nil {
+		t.Error("expected an error for an invalid join table name")
+	}
+
+	for table, want := range map[string]string{"categories": "category_id", "addresses": "address_id", "statuses": "status_id"} {
+		if got, err := joinKeyColumn(table); err != nil || got != want {
+			t.Errorf("joinKeyColumn(%q) = %q, %v; want %q", table, got, err, want)
+		}
+	}
+	if _, err := joinKeyColumn("staff"); err == nil {
+		t.Error("expected an error for a table name that is not a plural")
+	}
+}
+
+func TestJoinWritesOnUnknownModel(t *testing.T) {
+	var m ActiveRecordModel
+	for _, err := range []error{m.Append("Roles"), m.Remove("Roles"), m.Replace("Roles"), m.Clear("Roles")} {
+		if !errors.Is(err, ErrUnknownModel) {
+			t.Errorf("expected ErrUnknownModel, got %v", err)
+		}
+	}
+}
+
+func TestReplaceAssociationDryRun(t *testing.T) {
+	ann, _, admin, dev, _ := setupManyToManyTestDB(t)
+
+	c := NewDryRunCollector()
+	if err := ReplaceAssociationWithContext(DryRun(context.Background(), c), ann, "Roles", admin, dev); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	want := []string{
+		`DELETE FROM "roles_users" WHERE "user_id" = 1 AND "role_id" NOT IN (1, 2)`,
+		`SELECT "role_id" FROM "roles_users" WHERE "user_id" = 1 AND "role_id" IN (1, 2)`,
+		`INSERT INTO "roles_users" ("user_id", "role_id") VALUES (1, 1), (1, 2)`,
+	}
+	if got := c.SQL(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected statements:\n%s", strings.Join(got, "\n"))
+	}
+}
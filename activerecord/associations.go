@@ -1,8 +1,12 @@
 package activerecord
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 )
 
 // AssociationType type of association.
@@ -13,6 +17,8 @@ const (
 	HasMany
 	BelongsTo
 	HasManyThrough
+	// HasAndBelongsToMany links models through a join table without a model
+	HasAndBelongsToMany
 )
 
 // Association definition of association.
@@ -20,156 +26,335 @@ type Association struct {
 	Type       AssociationType
 	Model      interface{}
 	ForeignKey string
-	LocalKey   string
-	Through    string
+	// ForeignKeys lists the fields referencing a composite primary key,
+	// in key column order. It takes precedence over ForeignKey.
+	ForeignKeys []string
+	LocalKey    string
+	// Through is the join table of has-many-through and
+	// has-and-belongs-to-many associations, whose ForeignKey column
+	// references the owner and LocalKey column the associated model
+	Through string
+	// Order orders the associated records, as "column [ASC|DESC]"; a has-one
+	// association loads the first of them
+	Order string
 }
 
 // Associations map of associations for model.
 type Associations map[string]*Association
 
-// Association registry for test/demo.
-var associationRegistry = make(map[string]*Association)
+// modelAssociations holds the associations of a model type.
+type modelAssociations struct {
+	// declared are registered explicitly and take precedence over detected
+	declared Associations
+	// detected are declared by ar struct tags or inferred from relation
+	// fields, on first use
+	detected    Associations
+	detectErr   error
+	hasDetected bool
+}
+
+var (
+	associationsMu sync.RWMutex
+	// associationRegistry maps model types to their associations.
+	associationRegistry = make(map[reflect.Type]*modelAssociations)
+)
 
-// autoRegisterAssociations automatically detects and registers associations based on struct fields.
-func autoRegisterAssociations(model interface{}) {
-	val := reflect.ValueOf(model)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+// ErrUnknownModel is returned by association methods of an ActiveRecordModel
+// that load or write records but do not know the model embedding it, because
+// that model was not loaded or saved yet. Use the package functions taking
+// the model instead.
+var ErrUnknownModel = errors.New("the model embedding ActiveRecordModel is unknown until it is loaded or saved")
+
+// RegisterAssociation registers an association of a model type. It is safe
+// for concurrent use, but associations are best registered once at init.
+//
+//	func init() {
+//		RegisterAssociation(&User{}, "Posts", &Association{Type: HasMany, ForeignKey: "author_id"})
+//	}
+func RegisterAssociation(model interface{}, name string, association *Association) {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
-	if val.Kind() != reflect.Struct {
-		return
+	associationsMu.Lock()
+	defer associationsMu.Unlock()
+	associationsFor(t).declared[name] = association
+}
+
+// AssociationOf returns the association of a model with the given name:
+// a registered one, or else one declared by an ar struct tag such as
+//
+//	Posts []*Post `ar:"has_many,foreign_key=author_id"`
+//
+// or inferred from a relation field.
+func AssociationOf(model interface{}, name string) (*Association, error) {
+	adoptAssociations(model)
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
-	typ := val.Type()
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		fieldType := field.Type
-		if field.Anonymous && (fieldType.Kind() == reflect.Struct ||
-			(fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct)) {
-			// Embedded struct, recurse
-			var embeddedVal reflect.Value
-			if fieldType.Kind() == reflect.Ptr {
-				embeddedVal = val.Field(i)
-				if embeddedVal.IsNil() {
-					continue
-				}
-				embeddedVal = embeddedVal.Elem()
-			} else {
-				embeddedVal = val.Field(i)
-			}
-			autoRegisterAssociations(embeddedVal.Interface())
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a struct or a pointer to a struct, got %T", model)
+	}
+	return associationFor(t, name)
+}
+
+func associationFor(t reflect.Type, name string) (*Association, error) {
+	associationsMu.Lock()
+	defer associationsMu.Unlock()
+
+	m := associationsFor(t)
+	if a, ok := m.declared[name]; ok {
+		return a, nil
+	}
+	if !m.hasDetected {
+		m.detected, m.detectErr = detectAssociations(schemaFor(t))
+		m.hasDetected = true
+	}
+	if m.detectErr != nil {
+		return nil, m.detectErr
+	}
+	if a, ok := m.detected[name]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf("association %s not found", name)
+}
+
+// associationsFor returns the associations of a model type, creating them
+// if needed. The caller must hold associationsMu.
+func associationsFor(t reflect.Type) *modelAssociations {
+	m, ok := associationRegistry[t]
+	if !ok {
+		m = &modelAssociations{declared: make(Associations)}
+		associationRegistry[t] = m
+	}
+	return m
+}
+
+// associationTypes maps ar struct tag names to association types.
+var associationTypes = map[string]AssociationType{
+	"has_one":                 HasOne,
+	"has_many":                HasMany,
+	"belongs_to":              BelongsTo,
+	"has_many_through":        HasManyThrough,
+	"has_and_belongs_to_many": HasAndBelongsToMany,
+}
+
+// detectAssociations returns the associations declared by ar struct tags
+// or inferred from the relation fields of a model.
+func detectAssociations(schema *ModelSchema) (Associations, error) {
+	detected := make(Associations)
+	for _, rel := range schema.Relations {
+		tag, ok := schema.Type.FieldByIndex(rel.Index).Tag.Lookup("ar")
+		if !ok {
+			detected[rel.Name] = inferAssociation(schema, rel)
+			continue
 		}
-		// BelongsTo: *OtherModel
-		if fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct {
-			fk := field.Name + "ID"
-			modelPtr := reflect.New(fieldType.Elem()).Interface()
-			associationRegistry[field.Name] = &Association{
-				Type:       BelongsTo,
-				Model:      modelPtr,
-				ForeignKey: fk,
+		a, err := parseAssociationTag(schema, rel, tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", schema.Type.Name(), rel.Name, err)
+		}
+		detected[rel.Name] = a
+	}
+	return detected, nil
+}
+
+// parseAssociationTag parses an ar struct tag: the association type
+// followed by foreign_key, local_key, through (or join_table) and order
+// options. Several foreign
+// keys referencing a composite primary key are separated by spaces.
+func parseAssociationTag(schema *ModelSchema, rel *RelationField, tag string) (*Association, error) {
+	kind, options := parseTag(tag)
+	typ, ok := associationTypes[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown association type %q", kind)
+	}
+	a := inferAssociation(schema, rel)
+	a.Type = typ
+	switch typ {
+	case HasOne:
+		a.ForeignKey = schema.Type.Name() + "ID"
+	case HasAndBelongsToMany:
+		// inferred from the table names unless given
+		a.ForeignKey = ""
+	}
+	if fk, ok := options["foreign_key"]; ok {
+		keys := strings.Fields(fk)
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("empty foreign_key")
+		}
+		a.ForeignKey = keys[0]
+		if len(keys) > 1 {
+			a.ForeignKeys = keys
+		}
+	}
+	a.LocalKey = options["local_key"]
+	a.Through = options["through"]
+	if table, ok := options["join_table"]; ok {
+		a.Through = table
+	}
+	a.Order = options["order"]
+	if typ == HasManyThrough && (a.Through == "" || a.LocalKey == "") {
+		return nil, fmt.Errorf("has_many_through needs through and local_key")
+	}
+	if many := a.many(); many != rel.Many {
+		return nil, fmt.Errorf("%s does not match the field type %s", kind, rel.Type)
+	}
+	return a, nil
+}
+
+// many reports whether the association holds several records.
+func (a *Association) many() bool {
+	return a.Type == HasMany || a.Type == HasManyThrough || a.Type == HasAndBelongsToMany
+}
+
+// inferAssociation infers the association of a relation field: a pointer
+// belongs to the related model and a slice holds the models having the
+// parent's foreign key.
+func inferAssociation(schema *ModelSchema, rel *RelationField) *Association {
+	parentType := schema.Type.Name()
+	// BelongsTo: *OtherModel
+	if !rel.Many {
+		return &Association{
+			Type:       BelongsTo,
+			Model:      reflect.New(rel.Elem).Interface(),
+			ForeignKey: rel.Name + "ID",
+		}
+	}
+	// HasMany: []OtherModel or []*OtherModel
+	var fk string
+	if rel.Elem.Name() == parentType {
+		// Self-referencing: look for field ending with 'ID' but not 'ID'
+		for _, f := range schemaFor(rel.Elem).Fields {
+			if f.Name != "ID" && len(f.Name) > 2 && f.Name[len(f.Name)-2:] == "ID" {
+				fk = f.Name
+				break
 			}
 		}
-		// HasMany: []OtherModel или []*OtherModel
-		if fieldType.Kind() == reflect.Slice {
-			elem := fieldType.Elem()
-			if elem.Kind() == reflect.Struct || (elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct) {
-				parentType := typ.Name()
-				var elemTypeName string
-				if elem.Kind() == reflect.Ptr {
-					elemTypeName = elem.Elem().Name()
-				} else {
-					elemTypeName = elem.Name()
-				}
-				var fk string
-				if elemTypeName == parentType {
-					// Self-referencing: look for field ending with 'ID' but not 'ID'
-					if elem.Kind() == reflect.Ptr {
-						elem = elem.Elem()
-					}
-					found := false
-					for j := 0; j < elem.NumField(); j++ {
-						f := elem.Field(j)
-						if f.Name != "ID" && len(f.Name) > 2 && f.Name[len(f.Name)-2:] == "ID" {
-							fk = f.Name
-							found = true
-							break
-						}
-					}
-					if !found {
-						fieldName := field.Name
-						if len(fieldName) > 1 && fieldName[len(fieldName)-1] == 's' {
-							fieldName = fieldName[:len(fieldName)-1]
-						}
-						fk = fieldName + "ID"
-					}
-				} else {
-					fk = parentType + "ID"
-				}
-				slicePtr := reflect.New(fieldType).Interface()
-				associationRegistry[field.Name] = &Association{
-					Type:       HasMany,
-					Model:      slicePtr,
-					ForeignKey: fk,
-				}
+		if fk == "" {
+			fieldName := rel.Name
+			if len(fieldName) > 1 && fieldName[len(fieldName)-1] == 's' {
+				fieldName = fieldName[:len(fieldName)-1]
 			}
+			fk = fieldName + "ID"
 		}
+	} else {
+		fk = parentType + "ID"
+	}
+	return &Association{
+		Type:       HasMany,
+		Model:      reflect.New(rel.Type).Interface(),
+		ForeignKey: fk,
 	}
 }
 
+// register registers an association of the model embedding m. Until that
+// model is known, the association is kept on m and registered for its type
+// once the model is loaded, saved or passed to AssociationOf.
+func (m *ActiveRecordModel) register(name string, association *Association) {
+	if target := m.target(nil); target != nil {
+		RegisterAssociation(target, name, association)
+		return
+	}
+	if m.associations == nil {
+		m.associations = make(Associations)
+	}
+	m.associations[name] = association
+}
+
+// adoptAssociations registers the associations declared through the
+// ActiveRecordModel of model before model was known.
+func adoptAssociations(model interface{}) {
+	t, ok := model.(dirtyTracker)
+	if !ok {
+		return
+	}
+	ar := t.activeRecord()
+	for name, association := range ar.associations {
+		RegisterAssociation(model, name, association)
+	}
+	ar.associations = nil
+}
+
 // HasOne defines relationship "one to one".
 func (m *ActiveRecordModel) HasOne(name string, model interface{}, foreignKey string) {
-	associationRegistry[name] = &Association{
+	m.register(name, &Association{
 		Type:       HasOne,
 		Model:      model,
 		ForeignKey: foreignKey,
-	}
+	})
 }
 
 // HasMany defines relationship "one to many".
 func (m *ActiveRecordModel) HasMany(name string, model interface{}, foreignKey string) {
-	associationRegistry[name] = &Association{
+	m.register(name, &Association{
 		Type:       HasMany,
 		Model:      model,
 		ForeignKey: foreignKey,
-	}
+	})
 }
 
 // BelongsTo defines relationship "belongs to".
 func (m *ActiveRecordModel) BelongsTo(name string, model interface{}, foreignKey string) {
-	associationRegistry[name] = &Association{
+	m.register(name, &Association{
 		Type:       BelongsTo,
 		Model:      model,
 		ForeignKey: foreignKey,
-	}
+	})
 }
 
-// HasManyThrough defines relationship "many to many through".
+// BelongsToComposite defines relationship "belongs to" referencing a
+// composite primary key; foreignKeys are given in key column order.
+func (m *ActiveRecordModel) BelongsToComposite(name string, model interface{}, foreignKeys ...string) {
+	m.register(name, &Association{
+		Type:        BelongsTo,
+		Model:       model,
+		ForeignKeys: foreignKeys,
+	})
+}
+
+// HasManyThrough defines relationship "many to many through": the through
+// table's foreignKey column references the owner and its localKey column
+// references the associated model.
 func (m *ActiveRecordModel) HasManyThrough(name string, model interface{}, through string,
 	foreignKey string, localKey string) {
+	m.register(name, &Association{
+		Type:       HasManyThrough,
+		Model:      model,
+		ForeignKey: foreignKey,
+		LocalKey:   localKey,
+		Through:    through,
+	})
+}
+
+// HasAndBelongsToMany defines relationship "many to many" through a join
+// table without a model, whose foreignKey column references the owner and
+// localKey column the associated model. Empty names are inferred from the
+// table names: users and roles are joined by roles_users(user_id, role_id).
+func (m *ActiveRecordModel) HasAndBelongsToMany(name string, model interface{}, joinTable string,
+	foreignKey string, localKey string) {
+	m.register(name, &Association{
+		Type:       HasAndBelongsToMany,
+		Model:      model,
+		ForeignKey: foreignKey,
+		LocalKey:   localKey,
+		Through:    joinTable,
+	})
 }
 
 // Association methods for working with associations
 
 // Load loads association.
 func (m *ActiveRecordModel) Load(associationName string) error {
-	association, exists := m.getAssociation(associationName)
-	if !exists {
-		autoRegisterAssociations(m)
-		association, exists = m.getAssociation(associationName)
-		if !exists {
-			return fmt.Errorf("association %s not found", associationName)
-		}
+	target := m.target(nil)
+	if target == nil {
+		return ErrUnknownModel
 	}
-
-	switch association.Type {
-	case HasOne:
-		return m.loadHasOne(associationName, association)
-	case HasMany:
-		return m.loadHasMany(associationName, association)
-	case BelongsTo:
-		return m.loadBelongsTo(associationName, association)
-	default:
-		return fmt.Errorf("unsupported association type")
+	association, err := AssociationOf(target, associationName)
+	if err != nil {
+		return err
 	}
+	return loadAssociation(context.Background(), target, associationName, association)
 }
 
 // Include preloads associations.
@@ -184,76 +369,36 @@ func (m *ActiveRecordModel) Include(associationNames ...string) error {
 
 // Helper methods
 
-func (m *ActiveRecordModel) getAssociation(name string) (*Association, bool) {
-	assoc, ok := associationRegistry[name]
-	return assoc, ok
-}
-
-func (m *ActiveRecordModel) loadHasOne(name string, association *Association) error {
-	// Implementation of loading has_one.
-	return nil
-}
-
-func (m *ActiveRecordModel) loadHasMany(name string, association *Association) error {
-	// For test/demo: load all related records where foreignKey == m.ID.
-	foreignKey := association.ForeignKey
-	id := m.GetID()
-	query := foreignKey + " = ?"
+// loadAssociation loads an association of a model into its association
+// field or, when it has none, into association.Model: a pointer to a model,
+// set to the first associated record, or to a slice of models. Nothing is
+// loaded while the model's side of the key is unset.
+func loadAssociation(ctx context.Context, model interface{}, name string, association *Association) error {
+	val := reflect.ValueOf(model).Elem()
+	if _, ok := schemaFor(val.Type()).Relation(name); ok {
+		return PreloadWithContext(ctx, model, name)
+	}
 
-	// Try to set result directly to the field in the model.
-	val := reflect.ValueOf(m)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+	elem := modelStructType(association.Model)
+	if elem == nil {
+		return fmt.Errorf("association %s: model must be a pointer to a model or to a slice of models", name)
 	}
-	field := val.FieldByName(name)
-	if field.IsValid() && field.CanSet() {
-		// Create slice of the correct type.
-		sliceType := field.Type()
-		slicePtr := reflect.New(sliceType).Interface()
-		err := Where(slicePtr, query, id)
-		if err != nil {
-			return err
-		}
-		// Set the result to the field.
-		field.Set(reflect.ValueOf(slicePtr).Elem())
-		return nil
+	plan, err := newAssociationPlan(name, association, val.Type(), elem)
+	if err != nil {
+		return err
 	}
-
-	// Fallback to original behavior.
-	modelSlicePtr := association.Model
-	return Where(modelSlicePtr, query, id)
-}
-
-func (m *ActiveRecordModel) loadBelongsTo(name string, association *Association) error {
-	// For test/demo: load the related record by foreignKey.
-	foreignKey := association.ForeignKey
-	val := reflect.ValueOf(m).Elem().FieldByName(foreignKey)
-	if !val.IsValid() {
+	key, ok := plan.ownerKey(val)
+	if !ok {
 		return nil
 	}
-
-	// Try to set result directly to the field in the model.
-	modelVal := reflect.ValueOf(m)
-	if modelVal.Kind() == reflect.Ptr {
-		modelVal = modelVal.Elem()
+	qb, err := plan.query(ctx, [][]interface{}{key})
+	if err != nil {
+		return err
 	}
-	field := modelVal.FieldByName(name)
-	if field.IsValid() && field.CanSet() {
-		// Create instance of the correct type.
-		fieldType := field.Type()
-		instancePtr := reflect.New(fieldType.Elem()).Interface()
-		err := Find(instancePtr, val.Interface())
-		if err != nil {
-			return err
-		}
-		// Set the result to the field.
-		field.Set(reflect.ValueOf(instancePtr))
-		return nil
+	if reflect.TypeOf(association.Model).Elem().Kind() == reflect.Slice {
+		return qb.Find(association.Model)
 	}
-
-	// Fallback to original behavior.
-	modelPtr := association.Model
-	return Find(modelPtr, val.Interface())
+	return qb.First(association.Model)
 }
 
 // Join methods for working with JOIN.
@@ -280,12 +425,11 @@ func InnerJoins(models interface{}, joins ...string) error {
 
 // With preloads associations for collection.
 func With(models interface{}, associations ...string) error {
-	// Implementation of eager loading.
-	return nil
+	return PreloadWithContext(context.Background(), models, associations...)
 }
 
-// Preload preloads associations.
+// Preload preloads associations with one query per association; see
+// PreloadWithContext.
 func Preload(models interface{}, associations ...string) error {
-	// Implementation of preload.
-	return nil
+	return PreloadWithContext(context.Background(), models, associations...)
 }
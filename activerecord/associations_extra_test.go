@@ -61,16 +61,16 @@ func (a *Author) TableName() string { return "authors" }
 func (a *Author) Create() error     { return Create(a) }
 func (a *Author) Reload() error     { return Find(a, a.GetID()) }
 func (a *Author) HasMany(name string, model interface{}, foreignKey string) {
-	associationRegistry[name] = &Association{
+	RegisterAssociation(a, name, &Association{
 		Type:       HasMany,
 		Model:      model,
 		ForeignKey: foreignKey,
-	}
+	})
 }
 func (a *Author) Load(name string) error {
-	association, exists := associationRegistry[name]
-	if !exists {
-		return fmt.Errorf("association %s not found", name)
+	association, err := AssociationOf(a, name)
+	if err != nil {
+		return err
 	}
 	return loadHasMany(a, name, association)
 }
@@ -101,16 +101,16 @@ func (b *Book) TableName() string { return "books" }
 func (b *Book) Create() error     { return Create(b) }
 func (b *Book) Reload() error     { return Find(b, b.GetID()) }
 func (b *Book) BelongsTo(name string, model interface{}, foreignKey string) {
-	associationRegistry[name] = &Association{
+	RegisterAssociation(b, name, &Association{
 		Type:       BelongsTo,
 		Model:      model,
 		ForeignKey: foreignKey,
-	}
+	})
 }
 func (b *Book) Load(name string) error {
-	association, exists := associationRegistry[name]
-	if !exists {
-		return fmt.Errorf("association %s not found", name)
+	association, err := AssociationOf(b, name)
+	if err != nil {
+		return err
 	}
 	return loadBelongsTo(b, name, association)
 }
@@ -280,10 +280,9 @@ func (u *User) Reload() error              { return Find(u, u.GetID()) }
 func (u *User) SetMentor(mentor *User)     { u.Mentor = mentor }
 func (u *User) SetMentees(mentees []*User) { u.Mentees = mentees }
 func (u *User) Load(name string) error {
-	autoRegisterAssociations(u)
-	association, exists := associationRegistry[name]
-	if !exists {
-		return fmt.Errorf("association %s not found", name)
+	association, err := AssociationOf(u, name)
+	if err != nil {
+		return err
 	}
 
 	switch association.Type {
@@ -0,0 +1,120 @@
+package activerecord
+
+import (
+	"sync"
+	"testing"
+)
+
+type regUser struct {
+	ActiveRecordModel
+	Posts []*regPost `ar:"has_many,foreign_key=author_id"`
+}
+
+func (u *regUser) TableName() string { return "reg_users" }
+
+type regTag struct {
+	ActiveRecordModel
+	Posts []*regPost `ar:"has_many_through,through=taggings,foreign_key=tag_id,local_key=post_id"`
+}
+
+func (t *regTag) TableName() string { return "reg_tags" }
+
+type regPost struct {
+	ActiveRecordModel
+	AuthorID int64 `db:"author_id"`
+	Author   *regUser
+	Group    *regUser `ar:"belongs_to,foreign_key=GroupID OwnerID"`
+}
+
+func (p *regPost) TableName() string { return "reg_posts" }
+
+type regBadTag struct {
+	ActiveRecordModel
+	Posts []*regPost `ar:"has_one"`
+}
+
+func TestAssociationsPerModel(t *testing.T) {
+	userPosts, err := AssociationOf(&regUser{}, "Posts")
+	if err != nil || userPosts.Type != HasMany || userPosts.ForeignKey != "author_id" {
+		t.Fatalf("unexpected User.Posts: %+v, %v", userPosts, err)
+	}
+	tagPosts, err := AssociationOf(regTag{}, "Posts")
+	if err != nil || tagPosts.Type != HasManyThrough || tagPosts.Through != "taggings" ||
+		tagPosts.ForeignKey != "tag_id" || tagPosts.LocalKey != "post_id" {
+		t.Fatalf("unexpected Tag.Posts: %+v, %v", tagPosts, err)
+	}
+
+	if a, err := AssociationOf(&regPost{}, "Author"); err != nil || a.Type != BelongsTo || a.ForeignKey != "AuthorID" {
+		t.Errorf("unexpected inferred Post.Author: %+v, %v", a, err)
+	}
+	if a, err := AssociationOf(&regPost{}, "Group"); err != nil || len(a.ForeignKeys) != 2 || a.ForeignKeys[1] != "OwnerID" {
+		t.Errorf("unexpected Post.Group: %+v, %v", a, err)
+	}
+
+	// A registered association takes precedence over the tag
+	RegisterAssociation(&regUser{}, "Posts", &Association{Type: HasMany, ForeignKey: "writer_id"})
+	if a, _ := AssociationOf(&regUser{}, "Posts"); a.ForeignKey != "writer_id" {
+		t.Errorf("expected the registered association, got %+v", a)
+	}
+	if a, _ := AssociationOf(&regTag{}, "Posts"); a != tagPosts {
+		t.Errorf("registering User.Posts should not change Tag.Posts, got %+v", a)
+	}
+
+	if _, err := AssociationOf(&regUser{}, "Comments"); err == nil {
+		t.Error("expected an error for an unknown association")
+	}
+	if _, err := AssociationOf(&regBadTag{}, "Posts"); err == nil {
+		t.Error("expected an error for a has_one tag on a slice")
+	}
+}
+
+func TestAssociationsConcurrentUse(t *testing.T) {
+	type concurrentPost struct {
+		ActiveRecordModel
+		Author *regUser
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RegisterAssociation(&regTag{}, "Owner", &Association{Type: BelongsTo, ForeignKey: "OwnerID"})
+			if _, err := AssociationOf(&concurrentPost{}, "Author"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type regItemOwnerA struct {
+	ActiveRecordModel
+	Items []*regPost
+}
+
+type regItemOwnerB struct {
+	ActiveRecordModel
+}
+
+func TestAssociationMethodsRegisterPerModel(t *testing.T) {
+	// declared before the model is known, registered once it is
+	b := &regItemOwnerB{}
+	b.HasMany("Items", &[]*regPost{}, "b_id")
+	a := &regItemOwnerA{}
+	snapshotModel(a)
+	a.HasMany("Items", &[]*regPost{}, "a_id")
+
+	// the explicit association of A takes precedence over its inferred one
+	if got, err := AssociationOf(&regItemOwnerA{}, "Items"); err != nil || got.ForeignKey != "a_id" {
+		t.Errorf("expected A.Items by a_id, got %+v, %v", got, err)
+	}
+	if got, err := AssociationOf(b, "Items"); err != nil || got.ForeignKey != "b_id" {
+		t.Errorf("expected B.Items by b_id, got %+v, %v", got, err)
+	}
+	if got, err := AssociationOf(&regItemOwnerB{}, "Items"); err != nil || got.ForeignKey != "b_id" {
+		t.Errorf("expected B.Items registered for its type, got %+v, %v", got, err)
+	}
+	if _, err := AssociationOf(&ActiveRecordModel{}, "Items"); err == nil {
+		t.Error("associations of A and B should not apply to other models")
+	}
+}
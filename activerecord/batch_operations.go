@@ -2,6 +2,7 @@ package activerecord
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -22,6 +23,13 @@ func BatchInsert(models []interface{}) (*BatchInsertResult, error) {
 
 // BatchInsertWithContext performs batch insert with context
 func BatchInsertWithContext(ctx context.Context, models []interface{}) (*BatchInsertResult, error) {
+	return batchInsert(ctx, models, nil, nil)
+}
+
+// batchInsert inserts models one row at a time. With conflictFields, each
+// INSERT gets an ON CONFLICT clause updating updateFields, or doing nothing
+// when there are none.
+func batchInsert(ctx context.Context, models []interface{}, conflictFields, updateFields []string) (*BatchInsertResult, error) {
 	if len(models) == 0 {
 		return &BatchInsertResult{}, nil
 	}
@@ -33,6 +41,19 @@ func BatchInsertWithContext(ctx context.Context, models []interface{}) (*BatchIn
 		return nil, ErrNotModeler
 	}
 
+	val := reflect.Indirect(reflect.ValueOf(firstModel))
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a pointer to a struct")
+	}
+	schema := schemaFor(val.Type())
+	for _, fields := range [][]string{conflictFields, updateFields} {
+		for _, field := range fields {
+			if err := schema.checkColumn(field); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Set timestamps on all models first
 	now := time.Now()
 	for _, model := range models {
@@ -41,168 +62,182 @@ func BatchInsertWithContext(ctx context.Context, models []interface{}) (*BatchIn
 			m.SetUpdatedAt(now)
 		}
 	}
-
-	// Get fields and values for the first model (exclude ID)
-	fields, values := getFieldsAndValues(firstModel, true)
-	if len(fields) == 0 {
-		return nil, fmt.Errorf("no fields to insert")
+	for i, model := range models {
+		if err := schema.generateKey(reflect.Indirect(reflect.ValueOf(model))); err != nil {
+			return nil, fmt.Errorf("model at index %d: %w", i, err)
+		}
 	}
 
-	fmt.Printf("[DEBUG] BatchInsert: first model fields: %v\n", fields)
-	fmt.Printf("[DEBUG] BatchInsert: first model values: %v\n", values)
-
-	// Build the batch insert query
-	placeholders := make([]string, len(fields))
-	for i := range placeholders {
-		placeholders[i] = "?"
+	d := GetDialect()
+	upsert := len(conflictFields) > 0
+	var onConflict string
+	if upsert {
+		onConflict = fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(quoteIdentifiers(d, conflictFields), ", "))
+		if len(updateFields) > 0 {
+			updateClauses := make([]string, len(updateFields))
+			for i, field := range updateFields {
+				quoted := quoteIdentifier(d, field)
+				updateClauses[i] = fmt.Sprintf("%s = EXCLUDED.%s", quoted, quoted)
+			}
+			onConflict += fmt.Sprintf(" DO UPDATE SET %s", strings.Join(updateClauses, ", "))
+		} else {
+			onConflict += " DO NOTHING"
+		}
 	}
 
-	fmt.Printf("[DEBUG] BatchInsert: fields: %v\n", fields)
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		modeler.TableName(),
-		strings.Join(fields, ", "),
-		strings.Join(placeholders, ", "),
-	)
-	fmt.Printf("[DEBUG] BatchInsert: query: %s\n", query)
-
-	// Prepare the statement
-	stmt, err := GetConnection().PrepareContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare batch insert statement: %w", err)
+	// Rows can leave different columns to the database, such as a blank
+	// key next to a preset one, so a statement is prepared per column set.
+	returning := returningClause(d, schema)
+	stmts := make(map[string]preparedStmt)
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
+	prepare := func(fields []string) (preparedStmt, error) {
+		key := strings.Join(fields, ",")
+		if stmt, ok := stmts[key]; ok {
+			return stmt, nil
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			quoteTable(d, modeler.TableName()),
+			strings.Join(quoteIdentifiers(d, fields), ", "),
+			placeholders(len(fields)),
+		) + onConflict
+		if returning != "" {
+			query += " " + returning
+		}
+		stmt, err := prepareWithContext(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare batch insert statement: %w", err)
+		}
+		stmts[key] = stmt
+		return stmt, nil
 	}
-	defer stmt.Close()
 
-	// Execute batch insert
+	verb := "insert"
+	if upsert {
+		verb = "upsert"
+	}
 	var lastInsertID int64
 	var rowsAffected int64
-	var errors []error
+	var errs []error
 
 	for i, model := range models {
-		// Get values for this model (exclude ID)
-		_, values := getFieldsAndValues(model, true)
-		fmt.Printf("[DEBUG] BatchInsert: inserting values: %v\n", values)
+		modelVal := reflect.Indirect(reflect.ValueOf(model))
+		if modelVal.Type() != val.Type() {
+			errs = append(errs, fmt.Errorf("model at index %d has type %s, expected %s", i, modelVal.Type(), val.Type()))
+			continue
+		}
+		fields, values := schema.insertValues(modelVal)
+		if len(fields) == 0 {
+			errs = append(errs, fmt.Errorf("model at index %d has no fields to insert", i))
+			continue
+		}
+		stmt, err := prepare(fields)
+		if err != nil {
+			// Nothing has been inserted before the first statement
+			if len(stmts) == 0 {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("model at index %d: %w", i, err))
+			continue
+		}
+
+		// Read generated values back where LastInsertId is not supported
+		if returning != "" {
+			err := insertReturning(ctx, stmt, schema, modelVal, values)
+			switch {
+			case err == nil:
+				rowsAffected++
+			case inDryRun(ctx), upsert && errors.Is(err, errNoRowReturned):
+				// skipped by DO NOTHING
+			default:
+				errs = append(errs, fmt.Errorf("failed to %s model at index %d: %w", verb, i, err))
+			}
+			continue
+		}
 
 		// Execute insert
 		result, err := stmt.ExecContext(ctx, values...)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to insert model at index %d: %w", i, err))
+			errs = append(errs, fmt.Errorf("failed to %s model at index %d: %w", verb, i, err))
 			continue
 		}
+		affected, err := result.RowsAffected()
+		if err != nil || affected == 0 {
+			continue
+		}
+		rowsAffected += affected
 
-		// Get last insert ID and rows affected
-		if id, err := result.LastInsertId(); err == nil {
-			lastInsertID = id
+		// Set the generated ID on the model unless it has a key already.
+		// LastInsertId is not set for a row updated on conflict, so the key
+		// of an upserted row is read back by its conflict columns.
+		m, ok := model.(Modeler)
+		if !ok || !hasBlankKey(m) {
+			continue
 		}
-		if affected, err := result.RowsAffected(); err == nil {
-			rowsAffected += affected
+		if upsert {
+			if err := readUpsertedKey(ctx, d, schema, modeler.TableName(), modelVal, conflictFields); err != nil {
+				errs = append(errs, fmt.Errorf("failed to read the key of model at index %d: %w", i, err))
+			} else if id, ok := m.GetID().(int64); ok {
+				lastInsertID = id
+			}
+			continue
 		}
-
-		// Set the generated ID on the model
-		if m, ok := model.(Modeler); ok {
-			m.SetID(lastInsertID)
+		id, err := result.LastInsertId()
+		if err != nil {
+			continue
 		}
+		lastInsertID = id
+		m.SetID(id)
 	}
 
 	return &BatchInsertResult{
 		LastInsertID: lastInsertID,
 		RowsAffected: rowsAffected,
-		Errors:       errors,
+		Errors:       errs,
 	}, nil
 }
 
-// BatchUpsert performs batch upsert (insert or update) operation
-func BatchUpsert(models []interface{}, conflictFields []string, updateFields []string) (*BatchInsertResult, error) {
-	return BatchUpsertWithContext(context.Background(), models, conflictFields, updateFields)
+// insertReturning executes a prepared INSERT ... RETURNING statement and
+// scans the returned columns into the model value.
+func insertReturning(ctx context.Context, stmt preparedStmt, schema *ModelSchema, val reflect.Value, values []interface{}) error {
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanReturning(rows, schema, val)
 }
 
-// BatchUpsertWithContext performs batch upsert with context
-func BatchUpsertWithContext(ctx context.Context, models []interface{}, conflictFields []string, updateFields []string) (*BatchInsertResult, error) {
-	if len(models) == 0 {
-		return &BatchInsertResult{}, nil
+// readUpsertedKey reads the generated key of an upserted row, found by its
+// conflict columns, into the model value.
+func readUpsertedKey(ctx context.Context, d Dialect, schema *ModelSchema, table string, val reflect.Value, conflictFields []string) error {
+	if len(schema.PrimaryKeys) != 1 {
+		return nil
 	}
-
-	// Get the first model to determine structure
-	firstModel := models[0]
-	modeler, ok := firstModel.(Modeler)
+	pk := schema.PrimaryKeys[0]
+	field, ok := fieldByIndex(val, pk.Index, true)
 	if !ok {
-		return nil, ErrNotModeler
-	}
-
-	// Get fields and values for the first model
-	fields, _ := getFieldsAndValues(firstModel, false)
-	if len(fields) == 0 {
-		return nil, fmt.Errorf("no fields to insert")
-	}
-
-	// Build the batch upsert query
-	placeholders := make([]string, len(fields))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		modeler.TableName(),
-		strings.Join(fields, ", "),
-		strings.Join(placeholders, ", "),
-	)
-
-	// Add ON CONFLICT clause for PostgreSQL
-	if len(conflictFields) > 0 {
-		query += fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(conflictFields, ", "))
-
-		if len(updateFields) > 0 {
-			updateClauses := make([]string, len(updateFields))
-			for i, field := range updateFields {
-				updateClauses[i] = fmt.Sprintf("%s = EXCLUDED.%s", field, field)
-			}
-			query += fmt.Sprintf(" DO UPDATE SET %s", strings.Join(updateClauses, ", "))
-		} else {
-			query += " DO NOTHING"
-		}
-	}
-
-	// Prepare the statement
-	stmt, err := GetConnection().PrepareContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare batch upsert statement: %w", err)
+		return nil
 	}
-	defer stmt.Close()
-
-	// Execute batch upsert
-	var lastInsertID int64
-	var rowsAffected int64
-	var errors []error
-
-	for i, model := range models {
-		// Get values for this model
-		_, values := getFieldsAndValues(model, false)
-
-		// Execute upsert
-		result, err := stmt.ExecContext(ctx, values...)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to upsert model at index %d: %w", i, err))
-			continue
-		}
-
-		// Get last insert ID and rows affected
-		if id, err := result.LastInsertId(); err == nil {
-			lastInsertID = id
-		}
-		if affected, err := result.RowsAffected(); err == nil {
-			rowsAffected += affected
-		}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		quoteIdentifier(d, pk.Column), quoteTable(d, table), whereKey(d, conflictFields))
+	return QueryRowWithContext(ctx, query, schema.valuesFor(val, conflictFields)...).Scan(field.Addr().Interface())
+}
 
-		// Set the generated ID on the model
-		if m, ok := model.(Modeler); ok {
-			m.SetID(lastInsertID)
-		}
-	}
+// BatchUpsert performs batch upsert (insert or update) operation
+func BatchUpsert(models []interface{}, conflictFields []string, updateFields []string) (*BatchInsertResult, error) {
+	return BatchUpsertWithContext(context.Background(), models, conflictFields, updateFields)
+}
 
-	return &BatchInsertResult{
-		LastInsertID: lastInsertID,
-		RowsAffected: rowsAffected,
-		Errors:       errors,
-	}, nil
+// BatchUpsertWithContext performs batch upsert with context. Like
+// BatchInsertWithContext it generates keys and reads generated values back
+// with RETURNING where the dialect supports it. Rows skipped by DO NOTHING
+// keep a blank key and do not count as affected.
+func BatchUpsertWithContext(ctx context.Context, models []interface{}, conflictFields []string, updateFields []string) (*BatchInsertResult, error) {
+	return batchInsert(ctx, models, conflictFields, updateFields)
 }
 
 // FindInBatches processes records in batches
@@ -225,46 +260,9 @@ func FindInBatchesWithContext(ctx context.Context, modelType interface{}, batchS
 		return fmt.Errorf("receiver does not implement Modeler")
 	}
 
-	offset := 0
-
-	for {
-		// Create query builder for this batch
-		qb := NewQueryBuilder(modeler.TableName()).WithContext(ctx)
-		qb.Limit(batchSize).Offset(offset)
-
-		// Create slice for batch results
-		batchType := reflect.SliceOf(reflect.PtrTo(modelTypeValue))
-		batch := reflect.New(batchType).Interface()
-
-		// Execute query
-		err := qb.Find(batch)
-		if err != nil {
-			return fmt.Errorf("failed to find batch at offset %d: %w", offset, err)
-		}
-
-		// Convert to []interface{}
-		batchSlice := reflect.ValueOf(batch).Elem()
-		if batchSlice.Len() == 0 {
-			break
-		}
-		batchInterfaces := make([]interface{}, batchSlice.Len())
-		for i := 0; i < batchSlice.Len(); i++ {
-			batchInterfaces[i] = batchSlice.Index(i).Interface()
-		}
-
-		// Call the callback function
-		if err := fn(batchInterfaces); err != nil {
-			return fmt.Errorf("batch callback failed at offset %d: %w", offset, err)
-		}
-
-		if len(batchInterfaces) < batchSize {
-			break
-		}
-
-		offset += batchSize
-	}
-
-	return nil
+	qb := NewQueryBuilder(modeler.TableName()).WithContext(ctx)
+	qb.model = modelTypeValue
+	return qb.FindInBatches(batchSize, fn)
 }
 
 // FindOrCreate finds a record or creates it if it doesn't exist
@@ -278,38 +276,23 @@ func FindOrCreateWithContext(ctx context.Context, model interface{}, conditions
 	if !ok {
 		return ErrNotModeler
 	}
-
-	// Build where conditions
-	var whereClauses []string
-	var args []interface{}
-	for field, value := range conditions {
-		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", field))
-		args = append(args, value)
+	if err := schemaFor(modelStructType(model)).checkColumns(conditions); err != nil {
+		return err
 	}
 
-	if len(whereClauses) == 0 {
+	if len(conditions) == 0 {
 		return fmt.Errorf("no conditions provided for find or create")
 	}
 
-	// Try to find existing record
-	query := fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT 1",
-		modeler.TableName(),
-		strings.Join(whereClauses, " AND "),
-	)
-
-	rows, err := GetConnection().QueryContext(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("failed to query for existing record: %w", err)
-	}
-	defer rows.Close()
-
-	if rows.Next() {
-		// Record found, scan it
-		if err := scanRow(rows, model); err != nil {
-			return fmt.Errorf("failed to scan existing record: %w", err)
-		}
+	// Try to find an existing record through the model's scopes
+	err := modelQuery(ctx, modeler).WhereExpr(Conditions(conditions)).First(model)
+	if err == nil {
+		snapshotModel(model)
 		return nil
 	}
+	if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to query for existing record: %w", err)
+	}
 
 	// Record not found, create it
 	// Set the condition values on the model
@@ -328,7 +311,7 @@ func FindOrCreateWithContext(ctx context.Context, model interface{}, conditions
 	}
 
 	// Create the record
-	return Create(model)
+	return CreateWithContext(ctx, model)
 }
 
 // FindOrCreateByMap finds or creates records based on a map of attributes
@@ -373,32 +356,62 @@ func UpdateWithSQLExprAndContext(ctx context.Context, model interface{}, express
 		return ErrNotModeler
 	}
 
+	schema, err := SchemaOf(model)
+	if err != nil {
+		return err
+	}
+	for field := range expressions {
+		if err := schema.checkColumn(field); err != nil {
+			return err
+		}
+	}
+
 	// Set updated timestamp
 	modeler.SetUpdatedAt(time.Now())
 
 	// Build SET clause with SQL expressions
+	d := GetDialect()
 	var setClauses []string
 	for field, expr := range expressions {
-		setClauses = append(setClauses, fmt.Sprintf("%s = %s", field, expr))
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s", quoteIdentifier(d, field), expr))
 	}
 
 	if len(setClauses) == 0 {
 		return fmt.Errorf("no expressions provided for update")
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-		modeler.TableName(),
+	// Check and increment the lock version, if any
+	lock := lockFor(model)
+	allArgs := append([]interface{}{}, args...)
+	if lock != nil {
+		if _, ok := expressions[lock.column]; ok {
+			return fmt.Errorf("lock version column %s cannot be updated explicitly", lock.column)
+		}
+		setClauses = append(setClauses, quoteIdentifier(d, lock.column)+" = ?")
+		allArgs = append(allArgs, lock.version+1)
+	}
+
+	keyColumns, keyValues := primaryKey(modeler)
+	where, whereArgs := lock.where(d, whereKey(d, keyColumns), keyValues)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		quoteTable(d, modeler.TableName()),
 		strings.Join(setClauses, ", "),
+		where,
 	)
-
-	// Add ID to args
-	allArgs := append(args, modeler.GetID())
+	allArgs = append(allArgs, whereArgs...)
 
 	// Execute query
-	_, err := GetConnection().ExecContext(ctx, query, allArgs...)
+	result, err := ExecWithContext(ctx, query, allArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to update record with SQL expressions: %w", err)
 	}
+	if inDryRun(ctx) {
+		return nil
+	}
+	if err := lock.check(modeler.TableName(), keyValues, result); err != nil {
+		return err
+	}
+	lock.advance()
 
 	return nil
 }
@@ -416,25 +429,29 @@ func DeleteWithConditionsAndContext(ctx context.Context, modelType interface{},
 	if !ok {
 		return 0, ErrNotModeler
 	}
-
-	// Build where conditions
-	var whereClauses []string
-	var args []interface{}
-	for field, value := range conditions {
-		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", field))
-		args = append(args, value)
+	schema := schemaFor(reflect.TypeOf(temp).Elem())
+	if err := schema.checkColumns(conditions); err != nil {
+		return 0, err
 	}
 
-	if len(whereClauses) == 0 {
+	// Build where conditions
+	d := GetDialect()
+	where, args := Conditions(conditions).SQL(d)
+	if where == "" {
 		return 0, fmt.Errorf("no conditions provided for delete")
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s",
-		modeler.TableName(),
-		strings.Join(whereClauses, " AND "),
-	)
+	table := quoteTable(d, modeler.TableName())
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table, where)
 
-	result, err := GetConnection().ExecContext(ctx, query, args...)
+	// Soft-deletable models only have matching rows marked deleted
+	if deletedAt := schema.DeletedAt; deletedAt != nil {
+		query = fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s",
+			table, quoteIdentifier(d, deletedAt.Column), andNotDeleted(ctx, d, modeler, where))
+		args = append([]interface{}{time.Now()}, args...)
+	}
+
+	result, err := ExecWithContext(ctx, query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete records: %w", err)
 	}
@@ -454,47 +471,76 @@ func BulkUpdate(modelType interface{}, conditions map[string]interface{}, update
 
 // BulkUpdateWithContext performs bulk update with context
 func BulkUpdateWithContext(ctx context.Context, modelType interface{}, conditions map[string]interface{}, updates map[string]interface{}) (int64, error) {
+	return BulkUpdateWithOptions(ctx, modelType, conditions, updates, BulkUpdateOptions{})
+}
+
+// BulkUpdateOptions opts a bulk update into optimistic locking
+type BulkUpdateOptions struct {
+	// LockVersion increments the model's lock version column in every
+	// updated row
+	LockVersion bool
+	// ExpectedRows, when positive, makes the update fail with ErrStaleObject
+	// if a different number of rows was affected
+	ExpectedRows int64
+}
+
+// BulkUpdateWithOptions performs bulk update with context and locking options
+func BulkUpdateWithOptions(ctx context.Context, modelType interface{}, conditions map[string]interface{}, updates map[string]interface{}, opts BulkUpdateOptions) (int64, error) {
 	// Create a temporary instance to get table name
 	temp := reflect.New(reflect.TypeOf(modelType)).Interface()
 	modeler, ok := temp.(Modeler)
 	if !ok {
 		return 0, ErrNotModeler
 	}
+	schema := schemaFor(reflect.TypeOf(temp).Elem())
+	if err := schema.checkColumns(conditions); err != nil {
+		return 0, err
+	}
+	if err := schema.checkColumns(updates); err != nil {
+		return 0, err
+	}
 
 	// Build SET clause
+	d := GetDialect()
 	var setClauses []string
 	var setArgs []interface{}
 	for field, value := range updates {
-		setClauses = append(setClauses, fmt.Sprintf("%s = ?", field))
+		setClauses = append(setClauses, fmt.Sprintf("%s = ?", quoteIdentifier(d, field)))
 		setArgs = append(setArgs, value)
 	}
 
+	if opts.LockVersion {
+		lock := schema.LockVersion
+		if lock == nil {
+			return 0, fmt.Errorf("model %s has no lock version column", reflect.TypeOf(temp).Elem().Name())
+		}
+		if _, ok := updates[lock.Column]; ok {
+			return 0, fmt.Errorf("lock version column %s cannot be updated explicitly", lock.Column)
+		}
+		column := quoteIdentifier(d, lock.Column)
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s + 1", column, column))
+	}
+
 	if len(setClauses) == 0 {
 		return 0, fmt.Errorf("no updates provided")
 	}
 
 	// Build WHERE clause
-	var whereClauses []string
-	var whereArgs []interface{}
-	for field, value := range conditions {
-		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", field))
-		whereArgs = append(whereArgs, value)
-	}
-
-	if len(whereClauses) == 0 {
+	where, whereArgs := Conditions(conditions).SQL(d)
+	if where == "" {
 		return 0, fmt.Errorf("no conditions provided")
 	}
 
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
-		modeler.TableName(),
+		quoteTable(d, modeler.TableName()),
 		strings.Join(setClauses, ", "),
-		strings.Join(whereClauses, " AND "),
+		where,
 	)
 
 	// Combine args
 	allArgs := append(setArgs, whereArgs...)
 
-	result, err := GetConnection().ExecContext(ctx, query, allArgs...)
+	result, err := ExecWithContext(ctx, query, allArgs...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to bulk update: %w", err)
 	}
@@ -503,6 +549,10 @@ func BulkUpdateWithContext(ctx context.Context, modelType interface{}, condition
 	if err != nil {
 		return 0, fmt.Errorf("failed to get rows affected: %w", err)
 	}
+	// Nothing is affected in dry-run mode
+	if opts.ExpectedRows > 0 && rowsAffected != opts.ExpectedRows && !inDryRun(ctx) {
+		return rowsAffected, &StaleObjectError{Table: modeler.TableName()}
+	}
 
 	return rowsAffected, nil
 }
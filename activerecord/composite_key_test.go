@@ -0,0 +1,114 @@
+package activerecord
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type membership struct {
+	GroupID   int64     `db:"group_id,pk"`
+	UserID    int64     `db:"user_id,pk"`
+	Role      string    `db:"role"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (m *membership) TableName() string        { return "memberships" }
+func (m *membership) GetID() interface{}       { return CompositeKey{m.GroupID, m.UserID} }
+func (m *membership) SetID(id interface{})     {}
+func (m *membership) GetCreatedAt() time.Time  { return m.CreatedAt }
+func (m *membership) SetCreatedAt(t time.Time) { m.CreatedAt = t }
+func (m *membership) GetUpdatedAt() time.Time  { return m.UpdatedAt }
+func (m *membership) SetUpdatedAt(t time.Time) { m.UpdatedAt = t }
+func (m *membership) Find(id interface{}) error {
+	return Find(m, id)
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	if _, err := db.Exec(`CREATE TABLE memberships (
+		group_id INTEGER,
+		user_id INTEGER,
+		role TEXT,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP,
+		PRIMARY KEY (group_id, user_id)
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	schema, _ := SchemaOf(&membership{})
+	if got := schema.KeyColumns(); !reflect.DeepEqual(got, []string{"group_id", "user_id"}) {
+		t.Fatalf("unexpected key columns: %v", got)
+	}
+
+	for _, m := range []*membership{
+		{GroupID: 1, UserID: 1, Role: "owner"},
+		{GroupID: 1, UserID: 2, Role: "member"},
+	} {
+		if err := Create(m); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var found membership
+	if err := Find(&found, CompositeKey{1, 2}); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if found.Role != "member" {
+		t.Errorf("expected member, got %q", found.Role)
+	}
+
+	found.Role = "admin"
+	if err := Update(&found); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	owner := &membership{GroupID: 1, UserID: 1}
+	if err := Reload(owner); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if owner.Role != "owner" {
+		t.Errorf("update touched the wrong row, owner role is %q", owner.Role)
+	}
+
+	if err := Delete(&found); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := Find(&found, []interface{}{1, 2}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := Find(&found, 1); err == nil {
+		t.Error("expected error for a single value on a composite key")
+	}
+}
+
+func TestBelongsToCompositeKey(t *testing.T) {
+	type grant struct {
+		GroupID int64
+		UserID  int64
+	}
+	grantType, membershipType := reflect.TypeOf(grant{}), reflect.TypeOf(membership{})
+	assoc := &Association{Type: BelongsTo, ForeignKeys: []string{"GroupID", "UserID"}}
+	plan, err := newAssociationPlan("Membership", assoc, grantType, membershipType)
+	if err != nil {
+		t.Fatalf("newAssociationPlan failed: %v", err)
+	}
+	key, ok := plan.ownerKey(reflect.ValueOf(grant{GroupID: 3, UserID: 4}))
+	if !ok || !reflect.DeepEqual(key, []interface{}{int64(3), int64(4)}) {
+		t.Errorf("unexpected key: %#v", key)
+	}
+	if !reflect.DeepEqual(plan.keyColumns, []string{"group_id", "user_id"}) {
+		t.Errorf("unexpected key columns: %v", plan.keyColumns)
+	}
+
+	assoc.ForeignKeys = []string{"GroupID", "Missing"}
+	if _, err := newAssociationPlan("Membership", assoc, grantType, membershipType); err == nil {
+		t.Error("expected missing foreign key field to be reported")
+	}
+}
@@ -1,6 +1,7 @@
 package activerecord
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -64,25 +65,73 @@ func Begin() (*sql.Tx, error) {
 
 // Exec executes an SQL query without returning results.
 func Exec(query string, args ...interface{}) (sql.Result, error) {
+	return ExecWithContext(context.Background(), query, args...)
+}
+
+// Query executes an SQL query and returns results.
+func Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return QueryWithContext(context.Background(), query, args...)
+}
+
+// QueryRow executes an SQL query and returns a single row.
+func QueryRow(query string, args ...interface{}) *sql.Row {
+	return QueryRowWithContext(context.Background(), query, args...)
+}
+
+// ExecWithContext executes an SQL query with context without returning results.
+// "?" placeholders are rewritten for the current dialect. Under a DryRun
+// context the query is recorded instead.
+func ExecWithContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if c, ok := dryRunCollector(ctx); ok {
+		c.record(GetDialect(), Rebind(GetDialect(), query), args)
+		return dryRunResult{}, nil
+	}
 	if db == nil {
 		return nil, fmt.Errorf("no database connection")
 	}
-	return db.Exec(query, args...)
+	return db.ExecContext(ctx, Rebind(GetDialect(), query), args...)
 }
 
-// Query executes an SQL query and returns results.
-func Query(query string, args ...interface{}) (*sql.Rows, error) {
+// QueryWithContext executes an SQL query with context and returns results.
+// "?" placeholders are rewritten for the current dialect. Under a DryRun
+// context the query is recorded instead and ErrDryRun is returned.
+func QueryWithContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if c, ok := dryRunCollector(ctx); ok {
+		c.record(GetDialect(), Rebind(GetDialect(), query), args)
+		return nil, ErrDryRun
+	}
 	if db == nil {
 		return nil, fmt.Errorf("no database connection")
 	}
-	return db.Query(query, args...)
+	return db.QueryContext(ctx, Rebind(GetDialect(), query), args...)
 }
 
-// QueryRow executes an SQL query and returns a single row.
-func QueryRow(query string, args ...interface{}) *sql.Row {
+// QueryRowWithContext executes an SQL query with context and returns a single row.
+// "?" placeholders are rewritten for the current dialect.
+func QueryRowWithContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	if db == nil {
 		log.Printf("Warning: no database connection")
 		return nil
 	}
-	return db.QueryRow(query, args...)
+	return db.QueryRowContext(ctx, Rebind(GetDialect(), query), args...)
+}
+
+// preparedStmt is a prepared statement executed once per row of a batch.
+type preparedStmt interface {
+	ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error)
+	Close() error
+}
+
+// prepareWithContext prepares a statement with context.
+// "?" placeholders are rewritten for the current dialect. Under a DryRun
+// context each execution of the statement is recorded instead.
+func prepareWithContext(ctx context.Context, query string) (preparedStmt, error) {
+	if c, ok := dryRunCollector(ctx); ok {
+		return dryRunStmt{collector: c, dialect: GetDialect(), query: Rebind(GetDialect(), query)}, nil
+	}
+	if db == nil {
+		return nil, fmt.Errorf("no database connection")
+	}
+	return db.PrepareContext(ctx, Rebind(GetDialect(), query))
 }
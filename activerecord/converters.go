@@ -0,0 +1,280 @@
+package activerecord
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Converter assigns a raw database value to a field. src is nil for NULL.
+type Converter func(src interface{}, field reflect.Value) error
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(map[reflect.Type]Converter)
+)
+
+// RegisterConverter registers a converter for fields of the given type.
+// Registered converters take precedence over the built-in conversions.
+//
+//	RegisterConverter(reflect.TypeOf(Money{}), func(src interface{}, field reflect.Value) error {
+//		...
+//	})
+func RegisterConverter(t reflect.Type, c Converter) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	if c == nil {
+		delete(converters, t)
+		return
+	}
+	converters[t] = c
+}
+
+func lookupConverter(t reflect.Type) Converter {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	return converters[t]
+}
+
+// TimeParser is implemented by dialects whose drivers may return
+// timestamps as text.
+type TimeParser interface {
+	ParseTime(s string) (time.Time, error)
+}
+
+// defaultTimeLayouts are tried when the dialect has no TimeParser.
+var defaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTimeLayouts parses s with the first matching layout, in UTC unless
+// the value carries a zone.
+func parseTimeLayouts(s string, layouts []string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as time", s)
+}
+
+// assignValue converts a raw database value into field.
+func assignValue(d Dialect, field reflect.Value, src interface{}) error {
+	t := field.Type()
+	if c := lookupConverter(t); c != nil {
+		return c(src, field)
+	}
+
+	if field.CanAddr() {
+		if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+			return scanner.Scan(src)
+		}
+	}
+
+	if src == nil {
+		field.Set(reflect.Zero(t))
+		return nil
+	}
+
+	if t.Kind() == reflect.Ptr {
+		elem := reflect.New(t.Elem())
+		if err := assignValue(d, elem.Elem(), src); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	if t == timeType {
+		tm, err := asTime(d, src)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		field.SetString(asString(src))
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := asInt64(src)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows %v", n, t)
+		}
+		field.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := asUint64(src)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(n) {
+			return fmt.Errorf("value %d overflows %v", n, t)
+		}
+		field.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := asFloat64(src)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		b, err := asBool(src)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+		return nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			var b []byte
+			switch v := src.(type) {
+			case []byte:
+				b = append([]byte(nil), v...)
+			case string:
+				b = []byte(v)
+			default:
+				return fmt.Errorf("cannot convert %T to %v", src, t)
+			}
+			field.SetBytes(b)
+			return nil
+		}
+	}
+
+	val := reflect.ValueOf(src)
+	if val.Type().ConvertibleTo(t) {
+		field.Set(val.Convert(t))
+		return nil
+	}
+
+	return fmt.Errorf("cannot convert %v to %v", val.Type(), t)
+}
+
+func asString(src interface{}) string {
+	switch v := src.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func asInt64(src interface{}) (int64, error) {
+	switch v := src.(type) {
+	case int64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("cannot convert %v to integer without loss", v)
+		}
+		return int64(v), nil
+	case []byte, string:
+		return strconv.ParseInt(strings.TrimSpace(asString(v)), 10, 64)
+	}
+	rv := reflect.ValueOf(src)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to integer", src)
+}
+
+func asUint64(src interface{}) (uint64, error) {
+	switch v := src.(type) {
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("cannot convert negative value %d to unsigned integer", v)
+		}
+		return uint64(v), nil
+	case []byte, string:
+		return strconv.ParseUint(strings.TrimSpace(asString(v)), 10, 64)
+	}
+	rv := reflect.ValueOf(src)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	}
+	n, err := asInt64(src)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("cannot convert negative value %d to unsigned integer", n)
+	}
+	return uint64(n), nil
+}
+
+func asFloat64(src interface{}) (float64, error) {
+	switch v := src.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case []byte, string:
+		return strconv.ParseFloat(strings.TrimSpace(asString(v)), 64)
+	}
+	n, err := asInt64(src)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert %T to float", src)
+	}
+	return float64(n), nil
+}
+
+func asBool(src interface{}) (bool, error) {
+	switch v := src.(type) {
+	case bool:
+		return v, nil
+	case []byte, string:
+		return strconv.ParseBool(strings.TrimSpace(asString(v)))
+	}
+	n, err := asInt64(src)
+	if err != nil {
+		return false, fmt.Errorf("cannot convert %T to bool", src)
+	}
+	return n != 0, nil
+}
+
+func asTime(d Dialect, src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case []byte, string:
+		s := asString(v)
+		if p, ok := d.(TimeParser); ok {
+			return p.ParseTime(s)
+		}
+		return parseTimeLayouts(s, defaultTimeLayouts)
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %T to time.Time", src)
+}
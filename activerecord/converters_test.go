@@ -0,0 +1,170 @@
+package activerecord
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type upperString string
+
+func (u *upperString) Scan(src interface{}) error {
+	if src == nil {
+		*u = ""
+		return nil
+	}
+	*u = upperString(strings.ToUpper(asString(src)))
+	return nil
+}
+
+type cents int64
+
+type typedRecord struct {
+	ID       int64          `db:"id"`
+	Nickname *string        `db:"nickname"`
+	Visits   uint64         `db:"visits"`
+	Ratio    float32        `db:"ratio"`
+	Payload  []byte         `db:"payload"`
+	Seen     time.Time      `db:"seen"`
+	SeenAt   *time.Time     `db:"seen_at"`
+	Note     sql.NullString `db:"note"`
+	Code     upperString    `db:"code"`
+	Active   bool           `db:"active"`
+	Price    cents          `db:"price"`
+}
+
+func TestScanRowTypes(t *testing.T) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	if _, err := db.Exec(`CREATE TABLE typed_records (
+		id INTEGER PRIMARY KEY,
+		nickname TEXT,
+		visits INTEGER,
+		ratio REAL,
+		payload BLOB,
+		seen TEXT,
+		seen_at TEXT,
+		note TEXT,
+		code TEXT,
+		active INTEGER,
+		price TEXT
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO typed_records VALUES
+		(1, 'bob', 42, 0.5, x'0102', '2024-01-02 03:04:05', NULL, 'hi', 'abc', 1, '1999'),
+		(2, NULL, 0, NULL, NULL, '2024-01-02T03:04:05Z', '2024-02-03', NULL, NULL, 0, NULL)`); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	RegisterConverter(reflect.TypeOf(cents(0)), func(src interface{}, field reflect.Value) error {
+		if src == nil {
+			field.SetInt(0)
+			return nil
+		}
+		n, err := asInt64(src)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n * 100)
+		return nil
+	})
+	defer RegisterConverter(reflect.TypeOf(cents(0)), nil)
+
+	var records []typedRecord
+	rows, err := Query("SELECT * FROM typed_records ORDER BY id")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+	if err := scanRows(rows, &records); err != nil {
+		t.Fatalf("scanRows failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	first, second := records[0], records[1]
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if first.Nickname == nil || *first.Nickname != "bob" {
+		t.Errorf("unexpected nickname: %v", first.Nickname)
+	}
+	if first.Visits != 42 || first.Ratio != 0.5 || string(first.Payload) != "\x01\x02" {
+		t.Errorf("unexpected numeric/blob values: %+v", first)
+	}
+	if !first.Seen.Equal(want) || first.SeenAt != nil {
+		t.Errorf("unexpected times: %v %v", first.Seen, first.SeenAt)
+	}
+	if !first.Note.Valid || first.Note.String != "hi" || first.Code != "ABC" || !first.Active {
+		t.Errorf("unexpected scanner values: %+v", first)
+	}
+	if first.Price != 199900 {
+		t.Errorf("registered converter not used, got %d", first.Price)
+	}
+
+	if second.Nickname != nil || second.Payload != nil || second.Note.Valid || second.Code != "" {
+		t.Errorf("NULL values not mapped to zero: %+v", second)
+	}
+	if !second.Seen.Equal(want) {
+		t.Errorf("unexpected RFC3339 time: %v", second.Seen)
+	}
+	if second.SeenAt == nil || !second.SeenAt.Equal(time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date-only time: %v", second.SeenAt)
+	}
+}
+
+func TestSetFieldValue(t *testing.T) {
+	var m typedRecord
+	val := reflect.ValueOf(&m).Elem()
+
+	cases := []struct {
+		field string
+		value interface{}
+	}{
+		{"Visits", int64(7)},
+		{"Ratio", "1.25"},
+		{"Nickname", []byte("ann")},
+		{"Active", "true"},
+	}
+	for _, c := range cases {
+		if err := setFieldValue(val.FieldByName(c.field), c.value); err != nil {
+			t.Errorf("setFieldValue(%s, %v) failed: %v", c.field, c.value, err)
+		}
+	}
+	if m.Visits != 7 || m.Ratio != 1.25 || m.Nickname == nil || *m.Nickname != "ann" || !m.Active {
+		t.Errorf("unexpected values: %+v", m)
+	}
+
+	if err := setFieldValue(val.FieldByName("Visits"), int64(-1)); err == nil {
+		t.Error("expected error for negative unsigned value")
+	}
+	if err := setFieldValue(val.FieldByName("Nickname"), nil); err != nil || m.Nickname != nil {
+		t.Errorf("nil should clear pointer field, got %v (%v)", m.Nickname, err)
+	}
+}
+
+func TestDialectParseTime(t *testing.T) {
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, s := range []string{
+		"2024-01-02 03:04:05",
+		"2024-01-02T03:04:05Z",
+		"2024-01-02 03:04:05+00:00",
+	} {
+		got, err := (SQLiteDialect{}).ParseTime(s)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("sqlite ParseTime(%q) = %v, %v", s, got, err)
+		}
+	}
+
+	if got, err := (MySQLDialect{}).ParseTime("0000-00-00 00:00:00"); err != nil || !got.IsZero() {
+		t.Errorf("mysql zero date = %v, %v", got, err)
+	}
+	if _, err := (SQLiteDialect{}).ParseTime("yesterday"); err == nil {
+		t.Error("expected error for invalid time")
+	}
+}
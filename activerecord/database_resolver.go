@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -65,6 +66,11 @@ func (dc *DatabaseConnection) Close() error {
 	return nil
 }
 
+// Dialect returns the dialect of the connection's driver
+func (dc *DatabaseConnection) Dialect() Dialect {
+	return DialectFor(dc.config.Driver)
+}
+
 // HealthCheck performs a health check on the database
 func (dc *DatabaseConnection) HealthCheck() error {
 	dc.mu.RLock()
@@ -178,6 +184,24 @@ func (dr *DatabaseResolver) GetConnection(dbType DatabaseType) (*sql.DB, error)
 	}
 }
 
+// Dialect returns the dialect of the primary connection, falling back to
+// the first configured replica and then to the global connection's dialect
+func (dr *DatabaseResolver) Dialect() Dialect {
+	dr.mu.RLock()
+	defer dr.mu.RUnlock()
+
+	switch {
+	case dr.primary != nil:
+		return dr.primary.Dialect()
+	case len(dr.writeReplicas) > 0:
+		return dr.writeReplicas[0].Dialect()
+	case len(dr.readReplicas) > 0:
+		return dr.readReplicas[0].Dialect()
+	default:
+		return GetDialect()
+	}
+}
+
 // GetReadConnection returns a read connection (read replica or primary)
 func (dr *DatabaseResolver) GetReadConnection() (*sql.DB, error) {
 	return dr.GetConnection(ReadReplica)
@@ -296,6 +320,15 @@ func (dm *DatabaseManager) GetConnection(databaseName string, dbType DatabaseTyp
 	return resolver.GetConnection(dbType)
 }
 
+// GetDialect returns the dialect of a named database
+func (dm *DatabaseManager) GetDialect(databaseName string) Dialect {
+	resolver, err := dm.GetDatabase(databaseName)
+	if err != nil {
+		return GetDialect()
+	}
+	return resolver.Dialect()
+}
+
 // HealthCheck performs health checks on all databases
 func (dm *DatabaseManager) HealthCheck() map[string]map[string]error {
 	dm.mu.RLock()
@@ -355,7 +388,7 @@ func ExecOnDatabase(databaseName string, dbType DatabaseType, query string, args
 		return nil, err
 	}
 
-	return db.Exec(query, args...)
+	return db.Exec(Rebind(GetDatabaseManager().GetDialect(databaseName), query), args...)
 }
 
 // QueryOnDatabase executes a query on a specific database
@@ -365,7 +398,7 @@ func QueryOnDatabase(databaseName string, dbType DatabaseType, query string, arg
 		return nil, err
 	}
 
-	return db.Query(query, args...)
+	return db.Query(Rebind(GetDatabaseManager().GetDialect(databaseName), query), args...)
 }
 
 // QueryRowOnDatabase executes a query on a specific database and returns a single row
@@ -375,7 +408,7 @@ func QueryRowOnDatabase(databaseName string, dbType DatabaseType, query string,
 		return nil
 	}
 
-	return db.QueryRow(query, args...)
+	return db.QueryRow(Rebind(GetDatabaseManager().GetDialect(databaseName), query), args...)
 }
 
 // BeginTransactionOnDatabase begins a transaction on a specific database
@@ -412,35 +445,57 @@ func CreateOnDatabase(databaseName string, model interface{}) error {
 	modeler.SetCreatedAt(now)
 	modeler.SetUpdatedAt(now)
 
+	val := reflect.Indirect(reflect.ValueOf(model))
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to a struct")
+	}
+	schema := schemaFor(val.Type())
+	if err := schema.generateKey(val); err != nil {
+		return err
+	}
+
 	// Get fields and values
-	fields, values := getFieldsAndValues(model, false)
+	fields, values := schema.insertValues(val)
 	if len(fields) == 0 {
 		return fmt.Errorf("no fields to insert")
 	}
 
 	// Build query
-	placeholders := make([]string, len(fields))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
+	d := GetDatabaseManager().GetDialect(databaseName)
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		modeler.TableName(),
-		strings.Join(fields, ", "),
-		strings.Join(placeholders, ", "),
+		quoteTable(d, modeler.TableName()),
+		strings.Join(quoteIdentifiers(d, fields), ", "),
+		placeholders(len(fields)),
 	)
 
+	// Read generated values back where LastInsertId is not supported
+	if returning := returningClause(d, schema); returning != "" {
+		rows, err := QueryOnDatabase(databaseName, WriteReplica, query+" "+returning, values...)
+		if err != nil {
+			return fmt.Errorf("failed to create record: %w", err)
+		}
+		defer rows.Close()
+		if err := scanReturning(rows, schema, val); err != nil {
+			return err
+		}
+		snapshotModel(model)
+		return nil
+	}
+
 	// Execute query on write database
 	result, err := ExecOnDatabase(databaseName, WriteReplica, query, values...)
 	if err != nil {
 		return fmt.Errorf("failed to create record: %w", err)
 	}
 
-	// Set the generated ID
-	if id, err := result.LastInsertId(); err == nil {
-		modeler.SetID(id)
+	// Set the generated ID unless the key was supplied or generated client-side
+	if hasBlankKey(modeler) {
+		if id, err := result.LastInsertId(); err == nil {
+			modeler.SetID(id)
+		}
 	}
 
+	snapshotModel(model)
 	return nil
 }
 
@@ -451,8 +506,17 @@ func FindOnDatabase(databaseName string, model interface{}, id interface{}) erro
 		return ErrNotModeler
 	}
 
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = ?", modeler.TableName())
-	rows, err := QueryOnDatabase(databaseName, ReadReplica, query, id)
+	columns := primaryKeyColumns(model)
+	args, err := keyArgs(columns, id)
+	if err != nil {
+		return err
+	}
+
+	d := GetDatabaseManager().GetDialect(databaseName)
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s",
+		quoteTable(d, modeler.TableName()),
+		andNotDeleted(context.Background(), d, modeler, whereKey(d, columns)))
+	rows, err := QueryOnDatabase(databaseName, ReadReplica, query, args...)
 	if err != nil {
 		return err
 	}
@@ -461,7 +525,12 @@ func FindOnDatabase(databaseName string, model interface{}, id interface{}) erro
 	if !rows.Next() {
 		return ErrNotFound
 	}
-	return scanRow(rows, model)
+	if err := scanRow(rows, model); err != nil {
+		return err
+	}
+
+	snapshotModel(model)
+	return nil
 }
 
 // UpdateOnDatabase updates a record on a specific database
@@ -471,51 +540,77 @@ func UpdateOnDatabase(databaseName string, model interface{}) error {
 		return ErrNotModeler
 	}
 
-	// Set updated timestamp
-	modeler.SetUpdatedAt(time.Now())
-
-	// Get fields and values
-	fields, values := getFieldsAndValues(model, true)
+	// Get changed fields and values
+	fields, values, changed := updateValues(model, modeler)
+	if !changed {
+		return nil
+	}
 	if len(fields) == 0 {
 		return fmt.Errorf("no fields to update")
 	}
 
+	// Check and increment the lock version, if any
+	lock := lockFor(model)
+	fields, values = lock.set(fields, values)
+
 	// Build query
+	d := GetDatabaseManager().GetDialect(databaseName)
 	setClause := make([]string, len(fields))
 	for i, field := range fields {
-		setClause[i] = fmt.Sprintf("%s = ?", field)
+		setClause[i] = fmt.Sprintf("%s = ?", quoteIdentifier(d, field))
 	}
 
+	keyColumns, keyValues := primaryKey(modeler)
+	where, args := lock.where(d, whereKey(d, keyColumns), keyValues)
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE id = ?",
-		modeler.TableName(),
+		"UPDATE %s SET %s WHERE %s",
+		quoteTable(d, modeler.TableName()),
 		strings.Join(setClause, ", "),
+		where,
 	)
 
 	// Add ID to values
-	values = append(values, modeler.GetID())
+	values = append(values, args...)
 
 	// Execute query on write database
-	_, err := ExecOnDatabase(databaseName, WriteReplica, query, values...)
+	result, err := ExecOnDatabase(databaseName, WriteReplica, query, values...)
 	if err != nil {
 		return fmt.Errorf("failed to update record: %w", err)
 	}
+	if err := lock.check(modeler.TableName(), keyValues, result); err != nil {
+		return err
+	}
+	lock.advance()
 
+	snapshotModel(model)
 	return nil
 }
 
-// DeleteOnDatabase deletes a record on a specific database
+// DeleteOnDatabase deletes a record on a specific database, setting
+// deleted_at instead if it is soft-deletable
 func DeleteOnDatabase(databaseName string, model interface{}) error {
 	modeler, ok := model.(Modeler)
 	if !ok {
 		return ErrNotModeler
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", modeler.TableName())
-	_, err := ExecOnDatabase(databaseName, WriteReplica, query, modeler.GetID())
+	d := GetDatabaseManager().GetDialect(databaseName)
+	if t := modelStructType(model); t != nil && schemaFor(t).DeletedAt != nil {
+		exec := func(query string, args ...interface{}) (sql.Result, error) {
+			return ExecOnDatabase(databaseName, WriteReplica, query, args...)
+		}
+		now := time.Now()
+		return setDeletedAtOn(context.Background(), d, exec, model, &now)
+	}
+	lock := lockFor(model)
+	keyColumns, keyValues := primaryKey(modeler)
+	where, args := lock.where(d, whereKey(d, keyColumns), keyValues)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s",
+		quoteTable(d, modeler.TableName()), where)
+	result, err := ExecOnDatabase(databaseName, WriteReplica, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to delete record: %w", err)
 	}
 
-	return nil
+	return lock.check(modeler.TableName(), keyValues, result)
 }
@@ -0,0 +1,324 @@
+package activerecord
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dialect describes the SQL flavour spoken by a database driver.
+type Dialect interface {
+	// Name returns the dialect name.
+	Name() string
+	// Placeholder returns the bind parameter for the n-th argument (1-based).
+	Placeholder(n int) string
+	// QuoteIdentifier quotes a single identifier such as a table or column name.
+	QuoteIdentifier(name string) string
+	// LimitOffset renders the LIMIT/OFFSET clause, or "" when neither is set.
+	LimitOffset(limit, offset int) string
+	// BoolLiteral renders a boolean literal.
+	BoolLiteral(value bool) string
+	// TimeLiteral renders a timestamp literal.
+	TimeLiteral(t time.Time) string
+}
+
+// ReturningDialect is implemented by dialects that read generated values
+// back with INSERT ... RETURNING instead of LastInsertId.
+type ReturningDialect interface {
+	// Returning renders the RETURNING clause for the given columns.
+	Returning(columns []string) string
+}
+
+// SQLiteDialect is the dialect for the sqlite3 driver.
+type SQLiteDialect struct{}
+
+// Name returns the dialect name
+func (SQLiteDialect) Name() string { return "sqlite3" }
+
+// Placeholder returns the bind parameter for the n-th argument
+func (SQLiteDialect) Placeholder(n int) string { return "?" }
+
+// QuoteIdentifier quotes an identifier with double quotes
+func (SQLiteDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// LimitOffset renders the LIMIT/OFFSET clause; SQLite needs a LIMIT for OFFSET
+func (SQLiteDialect) LimitOffset(limit, offset int) string {
+	return limitOffset(limit, offset, "-1")
+}
+
+// BoolLiteral renders a boolean literal
+func (SQLiteDialect) BoolLiteral(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+// TimeLiteral renders a timestamp literal in the format used by go-sqlite3
+func (SQLiteDialect) TimeLiteral(t time.Time) string {
+	return "'" + t.Format("2006-01-02 15:04:05.999999999-07:00") + "'"
+}
+
+// sqliteTimeLayouts are the text timestamp formats accepted by go-sqlite3.
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses a timestamp stored as text
+func (SQLiteDialect) ParseTime(s string) (time.Time, error) {
+	return parseTimeLayouts(strings.TrimSuffix(s, "Z"), sqliteTimeLayouts)
+}
+
+// MySQLDialect is the dialect for the mysql driver.
+type MySQLDialect struct{}
+
+// Name returns the dialect name
+func (MySQLDialect) Name() string { return "mysql" }
+
+// Placeholder returns the bind parameter for the n-th argument
+func (MySQLDialect) Placeholder(n int) string { return "?" }
+
+// QuoteIdentifier quotes an identifier with backticks
+func (MySQLDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// LimitOffset renders the LIMIT/OFFSET clause; MySQL needs a LIMIT for OFFSET
+func (MySQLDialect) LimitOffset(limit, offset int) string {
+	return limitOffset(limit, offset, "18446744073709551615")
+}
+
+// BoolLiteral renders a boolean literal
+func (MySQLDialect) BoolLiteral(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// TimeLiteral renders a timestamp literal
+func (MySQLDialect) TimeLiteral(t time.Time) string {
+	return "'" + t.Format("2006-01-02 15:04:05.999999") + "'"
+}
+
+// ParseTime parses a DATETIME value returned without parseTime=true
+func (MySQLDialect) ParseTime(s string) (time.Time, error) {
+	if strings.HasPrefix(s, "0000-00-00") {
+		return time.Time{}, nil
+	}
+	return parseTimeLayouts(s, []string{"2006-01-02 15:04:05.999999", "2006-01-02"})
+}
+
+// PostgresDialect is the dialect for the postgres driver.
+type PostgresDialect struct{}
+
+// Name returns the dialect name
+func (PostgresDialect) Name() string { return "postgres" }
+
+// Placeholder returns the bind parameter for the n-th argument
+func (PostgresDialect) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+// QuoteIdentifier quotes an identifier with double quotes
+func (PostgresDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// LimitOffset renders the LIMIT/OFFSET clause
+func (PostgresDialect) LimitOffset(limit, offset int) string {
+	return limitOffset(limit, offset, "")
+}
+
+// BoolLiteral renders a boolean literal
+func (PostgresDialect) BoolLiteral(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// TimeLiteral renders a timestamp literal
+func (PostgresDialect) TimeLiteral(t time.Time) string {
+	return "'" + t.Format("2006-01-02 15:04:05.999999-07:00") + "'"
+}
+
+// Returning renders the RETURNING clause for the given columns
+func (d PostgresDialect) Returning(columns []string) string {
+	return "RETURNING " + strings.Join(quoteIdentifiers(d, columns), ", ")
+}
+
+// limitOffset renders LIMIT/OFFSET, using noLimit when only an offset is set.
+func limitOffset(limit, offset int, noLimit string) string {
+	var parts []string
+	switch {
+	case limit > 0:
+		parts = append(parts, fmt.Sprintf("LIMIT %d", limit))
+	case offset > 0 && noLimit != "":
+		parts = append(parts, "LIMIT "+noLimit)
+	}
+	if offset > 0 {
+		parts = append(parts, fmt.Sprintf("OFFSET %d", offset))
+	}
+	return strings.Join(parts, " ")
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{
+		"sqlite3":  SQLiteDialect{},
+		"mysql":    MySQLDialect{},
+		"postgres": PostgresDialect{},
+		"pgx":      PostgresDialect{},
+	}
+)
+
+// RegisterDialect registers a dialect for a driver name.
+func RegisterDialect(driver string, dialect Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[driver] = dialect
+}
+
+// DialectFor returns the dialect registered for a driver name.
+// Unknown drivers fall back to the SQLite dialect.
+func DialectFor(driver string) Dialect {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	if d, ok := dialects[driver]; ok {
+		return d
+	}
+	return SQLiteDialect{}
+}
+
+// GetDialect returns the dialect of the current connection.
+func GetDialect() Dialect {
+	return DialectFor(GetDriverName())
+}
+
+// Rebind rewrites "?" placeholders into the dialect's bind parameter style.
+// Question marks inside string literals, quoted identifiers and comments are
+// left untouched.
+func Rebind(d Dialect, query string) string {
+	if d.Placeholder(1) == "?" || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var out strings.Builder
+	out.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == '?':
+			n++
+			out.WriteString(d.Placeholder(n))
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// skipQuoted returns the index just past the quoted section starting at i.
+// Doubled quote characters are treated as escapes.
+func skipQuoted(s string, i int, quote byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != quote {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(s)
+}
+
+// quoteIdentifier quotes a possibly qualified identifier such as
+// "schema.table" or "users.id". Anything that is not a plain identifier
+// (expressions, aliases, "*") is returned unchanged.
+func quoteIdentifier(d Dialect, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "*" && i == len(parts)-1 && i > 0 {
+			continue
+		}
+		if !isPlainIdentifier(part) {
+			return name
+		}
+		parts[i] = d.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteTable quotes a possibly schema-qualified table name. Unlike
+// quoteIdentifier it quotes any name, so a table name cannot inject SQL.
+func quoteTable(d Dialect, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = d.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteIdentifiers quotes each identifier in names.
+func quoteIdentifiers(d Dialect, names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(d, name)
+	}
+	return quoted
+}
+
+func isPlainIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// placeholders returns n comma-separated "?" placeholders.
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
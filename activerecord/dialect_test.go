@@ -0,0 +1,119 @@
+package activerecord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDialectFor(t *testing.T) {
+	cases := map[string]string{
+		"sqlite3":  "sqlite3",
+		"mysql":    "mysql",
+		"postgres": "postgres",
+		"pgx":      "postgres",
+		"unknown":  "sqlite3",
+	}
+	for driver, want := range cases {
+		if got := DialectFor(driver).Name(); got != want {
+			t.Errorf("DialectFor(%q) = %s, want %s", driver, got, want)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	pg := PostgresDialect{}
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"SELECT * FROM users WHERE id = ?", "SELECT * FROM users WHERE id = $1"},
+		{"a = ? AND b IN (?, ?)", "a = $1 AND b IN ($2, $3)"},
+		{"name = '?' AND id = ?", "name = '?' AND id = $1"},
+		{"name = 'it''s ?' AND id = ?", "name = 'it''s ?' AND id = $1"},
+		{`"odd?col" = ?`, `"odd?col" = $1`},
+		{"id = ? -- why?\nAND x = ?", "id = $1 -- why?\nAND x = $2"},
+		{"id = ? /* ? */ AND x = ?", "id = $1 /* ? */ AND x = $2"},
+		{"id = $1", "id = $1"},
+	}
+	for _, c := range cases {
+		if got := Rebind(pg, c.in); got != c.want {
+			t.Errorf("Rebind(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	if got := Rebind(SQLiteDialect{}, "id = ?"); got != "id = ?" {
+		t.Errorf("sqlite Rebind should keep ?, got %q", got)
+	}
+}
+
+func TestDialectQuoting(t *testing.T) {
+	if got := quoteIdentifier(PostgresDialect{}, "public.users"); got != `"public"."users"` {
+		t.Errorf("unexpected postgres quoting: %s", got)
+	}
+	if got := quoteIdentifier(MySQLDialect{}, "users.*"); got != "`users`.*" {
+		t.Errorf("unexpected mysql quoting: %s", got)
+	}
+	if got := quoteIdentifier(SQLiteDialect{}, "COUNT(*)"); got != "COUNT(*)" {
+		t.Errorf("expressions should not be quoted, got %s", got)
+	}
+	if got := (MySQLDialect{}).QuoteIdentifier("we`ird"); got != "`we``ird`" {
+		t.Errorf("embedded quote not escaped: %s", got)
+	}
+}
+
+func TestDialectLimitOffset(t *testing.T) {
+	cases := []struct {
+		d      Dialect
+		limit  int
+		offset int
+		want   string
+	}{
+		{SQLiteDialect{}, 10, 0, "LIMIT 10"},
+		{SQLiteDialect{}, 0, 5, "LIMIT -1 OFFSET 5"},
+		{MySQLDialect{}, 0, 5, "LIMIT 18446744073709551615 OFFSET 5"},
+		{PostgresDialect{}, 0, 5, "OFFSET 5"},
+		{PostgresDialect{}, 10, 20, "LIMIT 10 OFFSET 20"},
+		{PostgresDialect{}, 0, 0, ""},
+	}
+	for _, c := range cases {
+		if got := c.d.LimitOffset(c.limit, c.offset); got != c.want {
+			t.Errorf("%s LimitOffset(%d, %d) = %q, want %q", c.d.Name(), c.limit, c.offset, got, c.want)
+		}
+	}
+}
+
+func TestDialectLiterals(t *testing.T) {
+	if (SQLiteDialect{}).BoolLiteral(true) != "1" || (PostgresDialect{}).BoolLiteral(false) != "FALSE" {
+		t.Error("unexpected boolean literals")
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := (MySQLDialect{}).TimeLiteral(ts); got != "'2024-01-02 03:04:05'" {
+		t.Errorf("unexpected mysql time literal: %s", got)
+	}
+	if got := (PostgresDialect{}).TimeLiteral(ts); got != "'2024-01-02 03:04:05+00:00'" {
+		t.Errorf("unexpected postgres time literal: %s", got)
+	}
+}
+
+func TestQueryBuilderBuildPostgres(t *testing.T) {
+	qb := NewQueryBuilder("users").SetDialect(PostgresDialect{})
+	qb.Where("age > ?", 18).
+		WhereIn("status", []interface{}{"active", "pending"}).
+		OrderBy("age", "desc").
+		Limit(10).
+		Offset(20)
+
+	query, args := qb.Build()
+	want := `SELECT * FROM "users" WHERE age > $1 AND status IN ($2, $3) ORDER BY "age" DESC LIMIT 10 OFFSET 20`
+	if query != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", query, want)
+	}
+	if len(args) != 3 {
+		t.Errorf("expected 3 args, got %d", len(args))
+	}
+
+	mysql, _ := NewQueryBuilder("users").SetDialect(MySQLDialect{}).Where("id = ?", 1).Offset(5).Build()
+	if mysql != "SELECT * FROM `users` WHERE id = ? LIMIT 18446744073709551615 OFFSET 5" {
+		t.Errorf("unexpected mysql query: %s", mysql)
+	}
+}
@@ -0,0 +1,228 @@
+package activerecord
+
+import (
+	"bytes"
+	"reflect"
+	"time"
+)
+
+// dirtyTracker is implemented by models embedding ActiveRecordModel.
+type dirtyTracker interface {
+	activeRecord() *ActiveRecordModel
+}
+
+var dirtyTrackerType = reflect.TypeOf((*dirtyTracker)(nil)).Elem()
+
+func (m *ActiveRecordModel) activeRecord() *ActiveRecordModel { return m }
+
+// target returns the model embedding m, as recorded when it was last loaded
+// or saved, or fallback when it is unknown or m has since been copied.
+func (m *ActiveRecordModel) target(fallback interface{}) interface{} {
+	if t, ok := m.self.(dirtyTracker); ok && t.activeRecord() == m {
+		return m.self
+	}
+	return fallback
+}
+
+// Change holds the persisted and current value of a changed attribute.
+type Change struct {
+	Old interface{}
+	New interface{}
+}
+
+// Changed reports whether any attribute changed since the model was last
+// loaded or saved.
+func (m *ActiveRecordModel) Changed() bool {
+	changes, _ := trackedChanges(m.target(m))
+	return len(changes) > 0
+}
+
+// ChangedAttributes returns the columns changed since the model was last
+// loaded or saved.
+func (m *ActiveRecordModel) ChangedAttributes() []string {
+	changes, _ := trackedChanges(m.target(m))
+	columns := make([]string, len(changes))
+	for i, c := range changes {
+		columns[i] = c.field.Column
+	}
+	return columns
+}
+
+// Changes returns the old and new values of changed attributes by column.
+func (m *ActiveRecordModel) Changes() map[string]Change {
+	changes, _ := trackedChanges(m.target(m))
+	result := make(map[string]Change, len(changes))
+	for _, c := range changes {
+		result[c.field.Column] = Change{Old: c.old, New: c.new}
+	}
+	return result
+}
+
+// WasChanged reports whether the attribute, given as column or field name,
+// changed since the model was last loaded or saved.
+func (m *ActiveRecordModel) WasChanged(field string) bool {
+	changes, _ := trackedChanges(m.target(m))
+	for _, c := range changes {
+		if c.field.Column == field || c.field.Name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotModel records the persisted attribute values of a model that
+// embeds ActiveRecordModel.
+func snapshotModel(model interface{}) {
+	t, ok := model.(dirtyTracker)
+	if !ok {
+		return
+	}
+	val := reflect.Indirect(reflect.ValueOf(model))
+	if val.Kind() != reflect.Struct {
+		return
+	}
+	schema := schemaFor(val.Type())
+	snapshot := make([]interface{}, len(schema.Fields))
+	for i, f := range schema.Fields {
+		snapshot[i] = fieldSnapshot(val, f)
+	}
+	ar := t.activeRecord()
+	ar.self = model
+	ar.snapshot = snapshot
+	adoptAssociations(model)
+}
+
+type fieldChange struct {
+	field    *FieldSchema
+	old, new interface{}
+}
+
+// trackedChanges compares a model with its snapshot. The bool is false when
+// the model is not tracked, in which case every column is considered dirty.
+func trackedChanges(model interface{}) ([]fieldChange, bool) {
+	t, ok := model.(dirtyTracker)
+	if !ok {
+		return nil, false
+	}
+	ar := t.activeRecord()
+	if ar.snapshot == nil || ar.self != model {
+		return nil, false
+	}
+	val := reflect.Indirect(reflect.ValueOf(model))
+	schema := schemaFor(val.Type())
+
+	var changes []fieldChange
+	for i, f := range schema.Fields {
+		current := fieldSnapshot(val, f)
+		if !valuesEqual(ar.snapshot[i], current) {
+			changes = append(changes, fieldChange{field: f, old: ar.snapshot[i], new: current})
+		}
+	}
+	return changes, true
+}
+
+// fieldSnapshot returns a copy of a field's value that later in-place
+// modifications do not affect.
+func fieldSnapshot(val reflect.Value, f *FieldSchema) interface{} {
+	fv, ok := fieldByIndex(val, f.Index, false)
+	if !ok {
+		return nil
+	}
+	return deepCopy(fv, make(map[uintptr]reflect.Value)).Interface()
+}
+
+// deepCopy returns a copy of v that shares no memory with it through
+// pointers, slices, maps or interfaces. Unexported struct fields are copied
+// as is. seen maps the pointers already copied to their copies.
+func deepCopy(v reflect.Value, seen map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if c, ok := seen[v.Pointer()]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[v.Pointer()] = c
+		c.Elem().Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		if sharesMemory(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				c.Index(i).Set(deepCopy(v.Index(i), seen))
+			}
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		if sharesMemory(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				c.Index(i).Set(deepCopy(v.Index(i), seen))
+			}
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value(), seen))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() && sharesMemory(v.Type().Field(i).Type) {
+				c.Field(i).Set(deepCopy(v.Field(i), seen))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// sharesMemory reports whether copying a value of type t by assignment can
+// leave the copy sharing memory with the original.
+func sharesMemory(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	case reflect.Array:
+		return sharesMemory(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if sharesMemory(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func valuesEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case time.Time:
+		bv, ok := b.(time.Time)
+		return ok && av.Equal(bv)
+	case []byte:
+		bv, ok := b.([]byte)
+		return ok && bytes.Equal(av, bv) && (av == nil) == (bv == nil)
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,151 @@
+package activerecord
+
+import (
+	"reflect"
+	"testing"
+)
+
+type article struct {
+	ActiveRecordModel
+	Title string `db:"title"`
+	Body  string `db:"body"`
+	Views int    `db:"views"`
+}
+
+func (a *article) TableName() string { return "articles" }
+
+func setupDirtyTestDB(t *testing.T) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	if _, err := db.Exec(`CREATE TABLE articles (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT,
+		body TEXT,
+		views INTEGER,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+}
+
+func TestDirtyTracking(t *testing.T) {
+	setupDirtyTestDB(t)
+
+	a := &article{Title: "Draft", Body: "Hello"}
+	if err := Create(a); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if a.Changed() {
+		t.Errorf("new record should be clean after Create, changes: %v", a.Changes())
+	}
+
+	a.Title = "Published"
+	if !a.Changed() || !a.WasChanged("title") || !a.WasChanged("Title") || a.WasChanged("body") {
+		t.Errorf("unexpected dirty state: %v", a.ChangedAttributes())
+	}
+	if got := a.ChangedAttributes(); !reflect.DeepEqual(got, []string{"title"}) {
+		t.Errorf("unexpected changed attributes: %v", got)
+	}
+	if c := a.Changes()["title"]; c.Old != "Draft" || c.New != "Published" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+
+	// A second copy edits a different column concurrently.
+	b := &article{}
+	if err := Find(b, a.GetID()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	b.Body = "Edited elsewhere"
+
+	if err := a.Update(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if a.Changed() {
+		t.Error("record should be clean after Update")
+	}
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	fresh := &article{}
+	if err := Find(fresh, a.GetID()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if fresh.Title != "Published" || fresh.Body != "Edited elsewhere" {
+		t.Errorf("partial updates clobbered each other: %+v", fresh)
+	}
+
+	// Nothing changed: no query, updated_at untouched.
+	updatedAt := fresh.UpdatedAt
+	if err := fresh.Update(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !fresh.UpdatedAt.Equal(updatedAt) {
+		t.Error("unchanged record should not be written")
+	}
+}
+
+func TestDirtyTrackingCollections(t *testing.T) {
+	setupDirtyTestDB(t)
+	for _, title := range []string{"One", "Two"} {
+		if err := Create(&article{Title: title}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var articles []*article
+	if err := Where(&articles, "title = ?", "Two"); err != nil {
+		t.Fatalf("Where failed: %v", err)
+	}
+	if len(articles) != 1 || articles[0].Changed() {
+		t.Fatalf("loaded records should be clean: %+v", articles)
+	}
+	articles[0].Views = 10
+	if !articles[0].WasChanged("views") {
+		t.Error("expected views to be changed")
+	}
+	if err := articles[0].Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := articles[0].Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if articles[0].Views != 10 || articles[0].Changed() {
+		t.Errorf("unexpected state after reload: %+v", articles[0])
+	}
+}
+
+type dirtyDoc struct {
+	ActiveRecordModel
+	Rating *int              `db:"rating"`
+	Tags   []string          `db:"tags"`
+	Meta   map[string]string `db:"meta"`
+	Data   []byte            `db:"data"`
+}
+
+func (d *dirtyDoc) TableName() string { return "dirty_docs" }
+
+func TestDirtyTrackingInPlaceEdits(t *testing.T) {
+	rating := 3
+	doc := &dirtyDoc{Rating: &rating, Tags: []string{"a", "b"}, Meta: map[string]string{"k": "v"}, Data: []byte("x")}
+	edits := map[string]func(){
+		"rating": func() { *doc.Rating = 4 },
+		"tags":   func() { doc.Tags[0] = "z" },
+		"meta":   func() { doc.Meta["k"] = "w" },
+		"data":   func() { doc.Data[0] = 'y' },
+	}
+	for column, edit := range edits {
+		snapshotModel(doc)
+		if doc.Changed() {
+			t.Fatalf("%s: expected a clean model after the snapshot", column)
+		}
+		edit()
+		if !doc.WasChanged(column) {
+			t.Errorf("expected an in-place edit of %s to be tracked", column)
+		}
+	}
+}
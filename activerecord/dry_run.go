@@ -0,0 +1,280 @@
+package activerecord
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDryRun is returned by reads in dry-run mode, whose statements are
+// recorded instead of executed, so an empty result is never mistaken for
+// a real one. Writes in dry-run mode succeed without changing the model.
+var ErrDryRun = errors.New("dry run: statement not executed")
+
+// Statement is an SQL statement recorded in dry-run mode
+type Statement struct {
+	// SQL has placeholders in the dialect's style
+	SQL     string
+	Args    []interface{}
+	Dialect Dialect
+}
+
+// String returns the statement with its arguments inlined as escaped
+// literals, or with the arguments appended when they cannot be inlined
+func (s Statement) String() string {
+	query, err := inlineArgs(s.Dialect, s.SQL, s.Args)
+	if err != nil {
+		return fmt.Sprintf("%s %v", s.SQL, s.Args)
+	}
+	return query
+}
+
+// DryRunCollector records the statements issued in dry-run mode. It is
+// safe for concurrent use.
+type DryRunCollector struct {
+	mu         sync.Mutex
+	statements []Statement
+}
+
+// NewDryRunCollector creates a new dry-run collector
+func NewDryRunCollector() *DryRunCollector {
+	return &DryRunCollector{}
+}
+
+// Statements returns the recorded statements in the order they were issued
+func (c *DryRunCollector) Statements() []Statement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Statement(nil), c.statements...)
+}
+
+// SQL returns the recorded statements with their arguments inlined
+func (c *DryRunCollector) SQL() []string {
+	statements := c.Statements()
+	queries := make([]string, len(statements))
+	for i, s := range statements {
+		queries[i] = s.String()
+	}
+	return queries
+}
+
+// Reset discards the recorded statements
+func (c *DryRunCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = nil
+}
+
+// record records a statement with placeholders in the dialect's style.
+// A nil collector discards it.
+func (c *DryRunCollector) record(d Dialect, query string, args []interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, Statement{
+		SQL:     query,
+		Args:    append([]interface{}(nil), args...),
+		Dialect: d,
+	})
+}
+
+type dryRunKey struct{}
+
+// DryRun returns a context under which statements issued by CRUD, batch
+// and query builder operations are recorded into c instead of executed.
+//
+//	c := NewDryRunCollector()
+//	err := CreateWithContext(DryRun(ctx, c), &user)
+//	fmt.Println(c.SQL())
+func DryRun(ctx context.Context, c *DryRunCollector) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, c)
+}
+
+// dryRunCollector returns the collector of a dry-run context.
+func dryRunCollector(ctx context.Context) (*DryRunCollector, bool) {
+	c, ok := ctx.Value(dryRunKey{}).(*DryRunCollector)
+	return c, ok
+}
+
+func inDryRun(ctx context.Context) bool {
+	_, ok := dryRunCollector(ctx)
+	return ok
+}
+
+// dryRunResult is the result of a write in dry-run mode.
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) { return 0, ErrDryRun }
+func (dryRunResult) RowsAffected() (int64, error) { return 0, nil }
+
+// dryRunStmt records each execution of a prepared statement in dry-run mode.
+type dryRunStmt struct {
+	collector *DryRunCollector
+	dialect   Dialect
+	query     string
+}
+
+func (s dryRunStmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	s.collector.record(s.dialect, s.query, args)
+	return dryRunResult{}, nil
+}
+
+func (s dryRunStmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	s.collector.record(s.dialect, s.query, args)
+	return nil, ErrDryRun
+}
+
+func (s dryRunStmt) Close() error { return nil }
+
+// DryRun records the statements of the query into c instead of executing
+// them. A nil c only prevents execution.
+//
+//	c := NewDryRunCollector()
+//	err := NewQueryBuilder("users").Where("age > ?", 18).DryRun(c).Find(&users)
+//	// err is ErrDryRun, c.SQL() is [SELECT * FROM "users" WHERE age > 18]
+func (qb *QueryBuilder) DryRun(c *DryRunCollector) *QueryBuilder {
+	qb.mode = DryRunMode
+	qb.collector = c
+	return qb
+}
+
+// dryRun returns the collector of the query and whether it runs in
+// dry-run mode, set on the query or by its context.
+func (qb *QueryBuilder) dryRun() (*DryRunCollector, bool) {
+	if qb.mode == DryRunMode {
+		if qb.collector == nil {
+			c, _ := dryRunCollector(qb.ctx)
+			return c, true
+		}
+		return qb.collector, true
+	}
+	return dryRunCollector(qb.ctx)
+}
+
+// ToSQL returns the query in the dialect's style with its arguments
+// inlined as escaped literals, for logging and debugging. Execute the
+// query with Build's placeholders and arguments instead.
+func (qb *QueryBuilder) ToSQL() (string, error) {
+	if err := qb.validate(); err != nil {
+		return "", err
+	}
+	query, args := qb.Build()
+	return inlineArgs(qb.getDialect(), query, args)
+}
+
+// inlineArgs replaces the placeholders of a query in the dialect's style
+// with args rendered as literals. Placeholders inside string literals,
+// quoted identifiers and comments are left untouched.
+func inlineArgs(d Dialect, query string, args []interface{}) (string, error) {
+	numbered := d.Placeholder(1) != "?"
+	var out strings.Builder
+	n := 0
+	arg := func(i int) error {
+		if i < 0 || i >= len(args) {
+			return fmt.Errorf("query has more placeholders than the %d arguments", len(args))
+		}
+		literal, err := sqlLiteral(d, args[i])
+		if err != nil {
+			return err
+		}
+		out.WriteString(literal)
+		return nil
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == '?' && !numbered:
+			if err := arg(n); err != nil {
+				return "", err
+			}
+			n++
+		case c == '$' && numbered && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			index, _ := strconv.Atoi(query[i+1 : j])
+			if err := arg(index - 1); err != nil {
+				return "", err
+			}
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), nil
+}
+
+// sqlLiteral renders an argument as an SQL literal of the dialect.
+func sqlLiteral(d Dialect, arg interface{}) (string, error) {
+	value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return "", fmt.Errorf("cannot inline argument %v: %w", arg, err)
+	}
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		return d.BoolLiteral(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("cannot inline argument %v: not a finite number", v)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		return stringLiteral(d, v)
+	case []byte:
+		if d.Name() == "postgres" {
+			return `'\x` + hex.EncodeToString(v) + `'::bytea`, nil
+		}
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case time.Time:
+		return d.TimeLiteral(v), nil
+	default:
+		return "", fmt.Errorf("cannot inline argument of type %T", arg)
+	}
+}
+
+// stringLiteral quotes a string, doubling single quotes. MySQL also treats
+// backslashes as escapes by default.
+func stringLiteral(d Dialect, s string) (string, error) {
+	if d.Name() == "mysql" {
+		s = strings.NewReplacer(`\`, `\\`, "\x00", `\0`, "\x1a", `\Z`).Replace(s)
+	} else if strings.ContainsRune(s, 0) {
+		return "", fmt.Errorf("cannot inline a string containing a NUL byte on %s", d.Name())
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+}
@@ -0,0 +1,155 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToSQL(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	qb := NewQueryBuilder("articles").
+		Where("title = ? AND body <> '?'", "it's").
+		Where("views > ? AND created_at < ?", 10, at).
+		WhereIn("id", []interface{}{1, 2})
+
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{SQLiteDialect{}, `SELECT * FROM "articles" WHERE (title = 'it''s' AND body <> '?') AND (views > 10 AND created_at < '2024-05-01 12:30:00+00:00') AND id IN (1, 2)`},
+		{PostgresDialect{}, `SELECT * FROM "articles" WHERE (title = 'it''s' AND body <> '?') AND (views > 10 AND created_at < '2024-05-01 12:30:00+00:00') AND id IN (1, 2)`},
+		{MySQLDialect{}, "SELECT * FROM `articles` WHERE (title = 'it''s' AND body <> '?') AND (views > 10 AND created_at < '2024-05-01 12:30:00') AND id IN (1, 2)"},
+	}
+	for _, tt := range tests {
+		got, err := qb.SetDialect(tt.dialect).ToSQL()
+		if err != nil {
+			t.Fatalf("%s: ToSQL failed: %v", tt.dialect.Name(), err)
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got: %s\nwant: %s", tt.dialect.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	var nilPtr *int
+	title := "x"
+	tests := []struct {
+		dialect Dialect
+		arg     interface{}
+		want    string
+	}{
+		{SQLiteDialect{}, nil, "NULL"},
+		{SQLiteDialect{}, nilPtr, "NULL"},
+		{SQLiteDialect{}, &title, "'x'"},
+		{SQLiteDialect{}, true, "1"},
+		{PostgresDialect{}, false, "FALSE"},
+		{SQLiteDialect{}, 1.5, "1.5"},
+		{SQLiteDialect{}, uint8(7), "7"},
+		{SQLiteDialect{}, `a\b`, `'a\b'`},
+		{MySQLDialect{}, `a\' OR 1=1 -- `, `'a\\'' OR 1=1 -- '`},
+		{SQLiteDialect{}, []byte{0xde, 0xad}, "X'dead'"},
+		{PostgresDialect{}, []byte{0xde, 0xad}, `'\xdead'::bytea`},
+	}
+	for _, tt := range tests {
+		got, err := sqlLiteral(tt.dialect, tt.arg)
+		if err != nil || got != tt.want {
+			t.Errorf("%s %#v: got %s, %v; want %s", tt.dialect.Name(), tt.arg, got, err, tt.want)
+		}
+	}
+
+	if _, err := sqlLiteral(SQLiteDialect{}, struct{}{}); err == nil {
+		t.Error("expected an error for a struct argument")
+	}
+	if _, err := sqlLiteral(PostgresDialect{}, "a\x00b"); err == nil {
+		t.Error("expected an error for a NUL byte")
+	}
+}
+
+func TestDryRunQueryBuilder(t *testing.T) {
+	setupDirtyTestDB(t)
+	if err := Create(&article{Title: "a"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	c := NewDryRunCollector()
+	var articles []*article
+	qb := NewQueryBuilder("articles").Where("title = ?", "a").DryRun(c)
+	if err := qb.Find(&articles); !errors.Is(err, ErrDryRun) || len(articles) != 0 {
+		t.Fatalf("expected ErrDryRun and no rows, got %v, %d rows", err, len(articles))
+	}
+	if _, err := qb.Count(); !errors.Is(err, ErrDryRun) {
+		t.Errorf("expected ErrDryRun from Count, got %v", err)
+	}
+	if n, err := qb.UpdateAll(map[string]interface{}{"views": 1}); err != nil || n != 0 {
+		t.Errorf("unexpected UpdateAll result: %d, %v", n, err)
+	}
+
+	want := []string{
+		`SELECT * FROM "articles" WHERE title = 'a'`,
+		`SELECT COUNT(*) FROM "articles" WHERE title = 'a'`,
+		`UPDATE "articles" SET "views" = 1 WHERE title = 'a'`,
+	}
+	if got := c.SQL(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", got, want)
+	}
+	if views, err := NewQueryBuilder("articles").Sum("views"); err != nil || views != 0 {
+		t.Errorf("dry run should not update rows, got %v, %v", views, err)
+	}
+}
+
+func TestDryRunCRUD(t *testing.T) {
+	setupDirtyTestDB(t)
+	c := NewDryRunCollector()
+	ctx := DryRun(context.Background(), c)
+
+	a := &article{Title: "draft"}
+	if err := CreateWithContext(ctx, a); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if a.GetID() != nil && a.GetID() != int64(0) {
+		t.Errorf("dry run should not assign an ID, got %v", a.GetID())
+	}
+	a.SetID(int64(1))
+	a.Title = "published"
+	if err := UpdateWithContext(ctx, a); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := DeleteWithContext(ctx, a); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	result, err := BatchInsertWithContext(ctx, []interface{}{&article{Title: "x"}, &article{Title: "y"}})
+	if err != nil || len(result.Errors) != 0 {
+		t.Fatalf("BatchInsert failed: %v, %v", err, result)
+	}
+	if err := FindWithContext(ctx, &article{}, 1); !errors.Is(err, ErrDryRun) {
+		t.Errorf("expected ErrDryRun from Find, got %v", err)
+	}
+
+	statements := c.Statements()
+	if len(statements) != 6 {
+		t.Fatalf("expected 6 statements, got %q", c.SQL())
+	}
+	for i, prefix := range []string{"INSERT INTO", "UPDATE", "DELETE FROM", "INSERT INTO", "INSERT INTO", "SELECT"} {
+		if got := statements[i].SQL; !strings.HasPrefix(got, prefix) {
+			t.Errorf("statement %d: expected %s, got %s", i, prefix, got)
+		}
+	}
+	if got := statements[4].String(); !strings.Contains(got, "'y'") {
+		t.Errorf("unexpected batch statement: %s", got)
+	}
+
+	var count int
+	if err := GetConnection().QueryRow("SELECT COUNT(*) FROM articles").Scan(&count); err != nil || count != 0 {
+		t.Errorf("dry run should not write rows, got %d, %v", count, err)
+	}
+
+	c.Reset()
+	if len(c.Statements()) != 0 {
+		t.Error("Reset should discard statements")
+	}
+}
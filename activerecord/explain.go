@@ -0,0 +1,307 @@
+package activerecord
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrExplainAnalyzeUnsupported is returned by ExplainAnalyze for databases
+// that cannot run a query to report its actual plan.
+var ErrExplainAnalyzeUnsupported = errors.New("explain analyze is not supported")
+
+// QueryPlan is the plan of a query as reported by EXPLAIN
+type QueryPlan struct {
+	Query string
+	// Analyzed is set when the query was run and Rows are actual counts
+	Analyzed bool
+	// Nodes lists the plan's steps depth first
+	Nodes []PlanNode
+	// Raw is the database's own rendering of the plan
+	Raw string
+}
+
+// PlanNode is a step of a query plan
+type PlanNode struct {
+	// Depth is the nesting level of the step, 0 for top-level steps
+	Depth  int
+	Detail string
+	Table  string
+	// Index is the index used to access Table, if any
+	Index string
+	// Rows is the estimated number of rows, or the actual number when
+	// analyzed; 0 when the database does not report it
+	Rows int64
+	// FullScan is set when every row of Table is read
+	FullScan bool
+}
+
+// UsesIndex reports whether any step of the plan uses an index
+func (p *QueryPlan) UsesIndex() bool {
+	for _, n := range p.Nodes {
+		if n.Index != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FullScans returns the tables read in full by the plan
+func (p *QueryPlan) FullScans() []string {
+	var tables []string
+	for _, n := range p.Nodes {
+		if n.FullScan {
+			tables = append(tables, n.Table)
+		}
+	}
+	return tables
+}
+
+// String returns the database's rendering of the plan
+func (p *QueryPlan) String() string {
+	return p.Raw
+}
+
+// Explain returns the plan of the query without running it
+func (qb *QueryBuilder) Explain(ctx context.Context) (*QueryPlan, error) {
+	return qb.explain(ctx, false)
+}
+
+// ExplainAnalyze runs the query and returns its plan with actual row
+// counts. It is supported on PostgreSQL and MySQL 8.0.18 and later.
+func (qb *QueryBuilder) ExplainAnalyze(ctx context.Context) (*QueryPlan, error) {
+	return qb.explain(ctx, true)
+}
+
+func (qb *QueryBuilder) explain(ctx context.Context, analyze bool) (*QueryPlan, error) {
+	if err := qb.validate(); err != nil {
+		return nil, err
+	}
+	query, args := qb.Build()
+	return explain(ctx, qb.getDialect(), query, args, analyze)
+}
+
+// ExplainQuery returns the plan of an SQL query with "?" placeholders
+// without running it
+func ExplainQuery(ctx context.Context, query string, args ...interface{}) (*QueryPlan, error) {
+	d := GetDialect()
+	return explain(ctx, d, Rebind(d, query), args, false)
+}
+
+// explain runs the dialect's EXPLAIN form of a bound query.
+func explain(ctx context.Context, d Dialect, query string, args []interface{}, analyze bool) (*QueryPlan, error) {
+	var prefix string
+	switch d.Name() {
+	case "sqlite3":
+		if analyze {
+			return nil, fmt.Errorf("%w on sqlite3", ErrExplainAnalyzeUnsupported)
+		}
+		prefix = "EXPLAIN QUERY PLAN "
+	case "postgres":
+		prefix = "EXPLAIN (FORMAT JSON) "
+		if analyze {
+			prefix = "EXPLAIN (ANALYZE, FORMAT JSON) "
+		}
+	case "mysql":
+		prefix = "EXPLAIN "
+		if analyze {
+			prefix = "EXPLAIN ANALYZE "
+		}
+	default:
+		return nil, fmt.Errorf("explain is not supported on %s", d.Name())
+	}
+
+	rows, err := GetConnection().QueryContext(ctx, prefix+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	defer rows.Close()
+
+	plan := &QueryPlan{Query: query, Analyzed: analyze}
+	switch {
+	case d.Name() == "sqlite3":
+		err = parseSQLitePlan(rows, plan)
+	case d.Name() == "postgres":
+		err = parsePostgresPlan(rows, plan)
+	case analyze:
+		err = parseMySQLAnalyze(rows, plan)
+	default:
+		err = parseMySQLPlan(rows, plan)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query plan: %w", err)
+	}
+	return plan, nil
+}
+
+// parseSQLitePlan reads EXPLAIN QUERY PLAN rows of id, parent, notused and
+// detail, such as "SEARCH users USING INDEX idx_email (email=?)".
+func parseSQLitePlan(rows *sql.Rows, plan *QueryPlan) error {
+	depths := make(map[int64]int)
+	var lines []string
+	for rows.Next() {
+		var id, parent, notUsed int64
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return err
+		}
+		node := PlanNode{Depth: depths[parent], Detail: detail}
+		depths[id] = node.Depth + 1
+
+		words := strings.Fields(detail)
+		if len(words) > 1 && (words[0] == "SCAN" || words[0] == "SEARCH") {
+			node.Table = words[1]
+			if words[1] == "TABLE" && len(words) > 2 {
+				node.Table = words[2]
+			}
+			if i := strings.Index(detail, " INDEX "); i >= 0 {
+				node.Index = strings.Fields(detail[i+len(" INDEX "):])[0]
+			} else if strings.Contains(detail, " INTEGER PRIMARY KEY") {
+				node.Index = "INTEGER PRIMARY KEY"
+			}
+			node.FullScan = words[0] == "SCAN" && node.Index == ""
+		}
+		plan.Nodes = append(plan.Nodes, node)
+		lines = append(lines, strings.Repeat("  ", node.Depth)+detail)
+	}
+	plan.Raw = strings.Join(lines, "\n")
+	return rows.Err()
+}
+
+// postgresNode is a node of a PostgreSQL JSON plan.
+type postgresNode struct {
+	NodeType    string         `json:"Node Type"`
+	Relation    string         `json:"Relation Name"`
+	Index       string         `json:"Index Name"`
+	PlanRows    float64        `json:"Plan Rows"`
+	ActualRows  *float64       `json:"Actual Rows"`
+	ActualLoops float64        `json:"Actual Loops"`
+	Plans       []postgresNode `json:"Plans"`
+}
+
+func parsePostgresPlan(rows *sql.Rows, plan *QueryPlan) error {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("no plan returned")
+	}
+	var raw []byte
+	if err := rows.Scan(&raw); err != nil {
+		return err
+	}
+	var result []struct {
+		Plan postgresNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return err
+	}
+	plan.Raw = string(raw)
+
+	var walk func(n postgresNode, depth int)
+	walk = func(n postgresNode, depth int) {
+		node := PlanNode{
+			Depth:    depth,
+			Detail:   n.NodeType,
+			Table:    n.Relation,
+			Index:    n.Index,
+			Rows:     int64(n.PlanRows),
+			FullScan: n.NodeType == "Seq Scan",
+		}
+		if n.ActualRows != nil {
+			node.Rows = int64(*n.ActualRows * n.ActualLoops)
+		}
+		plan.Nodes = append(plan.Nodes, node)
+		for _, child := range n.Plans {
+			walk(child, depth+1)
+		}
+	}
+	for _, r := range result {
+		walk(r.Plan, 0)
+	}
+	return rows.Err()
+}
+
+// parseMySQLPlan reads the rows of MySQL's tabular EXPLAIN output.
+func parseMySQLPlan(rows *sql.Rows, plan *QueryPlan) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[strings.ToLower(column)] = values[i].String
+		}
+
+		node := PlanNode{
+			Detail:   strings.TrimSpace(row["select_type"] + " " + row["type"] + " " + row["extra"]),
+			Table:    row["table"],
+			Index:    row["key"],
+			FullScan: row["type"] == "ALL",
+		}
+		node.Rows, _ = strconv.ParseInt(row["rows"], 10, 64)
+		plan.Nodes = append(plan.Nodes, node)
+		lines = append(lines, fmt.Sprintf("%s %s (key: %s, rows: %s)", node.Table, node.Detail, node.Index, row["rows"]))
+	}
+	plan.Raw = strings.Join(lines, "\n")
+	return rows.Err()
+}
+
+var (
+	mysqlTableRe  = regexp.MustCompile(` on (\S+)`)
+	mysqlIndexRe  = regexp.MustCompile(` using (\S+)`)
+	mysqlActualRe = regexp.MustCompile(`actual time=\S+ rows=(\d+)`)
+)
+
+// parseMySQLAnalyze reads the tree of EXPLAIN ANALYZE, one step per line
+// such as "-> Index lookup on users using idx_email (email='a')".
+func parseMySQLAnalyze(rows *sql.Rows, plan *QueryPlan) error {
+	var out strings.Builder
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return err
+		}
+		out.WriteString(text)
+	}
+	plan.Raw = out.String()
+
+	for _, line := range strings.Split(plan.Raw, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, "-> ") {
+			continue
+		}
+		detail := strings.TrimPrefix(trimmed, "-> ")
+		node := PlanNode{
+			Depth:    (len(line) - len(trimmed)) / 4,
+			Detail:   detail,
+			FullScan: strings.HasPrefix(detail, "Table scan"),
+		}
+		if m := mysqlTableRe.FindStringSubmatch(detail); m != nil {
+			node.Table = m[1]
+		}
+		if m := mysqlIndexRe.FindStringSubmatch(detail); m != nil {
+			node.Index = m[1]
+		}
+		if m := mysqlActualRe.FindStringSubmatch(detail); m != nil {
+			node.Rows, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		plan.Nodes = append(plan.Nodes, node)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,165 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExplainSQLite(t *testing.T) {
+	setupDirtyTestDB(t)
+	ctx := context.Background()
+
+	plan, err := NewQueryBuilder("articles").Where("title = ?", "Go").Explain(ctx)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if plan.UsesIndex() || !reflect.DeepEqual(plan.FullScans(), []string{"articles"}) {
+		t.Errorf("expected a full scan, got %+v", plan.Nodes)
+	}
+
+	if _, err := GetConnection().Exec("CREATE INDEX idx_articles_title ON articles (title)"); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	plan, err = NewQueryBuilder("articles").Where("title = ?", "Go").Explain(ctx)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if len(plan.Nodes) == 0 || plan.Nodes[0].Index != "idx_articles_title" || plan.Nodes[0].Table != "articles" {
+		t.Errorf("expected the title index to be used, got %+v", plan.Nodes)
+	}
+
+	if _, err := NewQueryBuilder("articles").ExplainAnalyze(ctx); !errors.Is(err, ErrExplainAnalyzeUnsupported) {
+		t.Errorf("expected ErrExplainAnalyzeUnsupported, got %v", err)
+	}
+}
+
+func TestParsePlans(t *testing.T) {
+	setupDirtyTestDB(t)
+	db := GetConnection()
+
+	rows, err := db.Query(`SELECT '[{"Plan": {"Node Type": "Nested Loop", "Plan Rows": 5, "Plans": [` +
+		`{"Node Type": "Seq Scan", "Relation Name": "users", "Plan Rows": 5, "Actual Rows": 2, "Actual Loops": 1},` +
+		`{"Node Type": "Index Scan", "Relation Name": "orders", "Index Name": "orders_user_id", "Plan Rows": 1}]}}]'`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	plan := &QueryPlan{}
+	if err := parsePostgresPlan(rows, plan); err != nil {
+		t.Fatalf("parsePostgresPlan failed: %v", err)
+	}
+	rows.Close()
+	want := []PlanNode{
+		{Depth: 0, Detail: "Nested Loop", Rows: 5},
+		{Depth: 1, Detail: "Seq Scan", Table: "users", Rows: 2, FullScan: true},
+		{Depth: 1, Detail: "Index Scan", Table: "orders", Index: "orders_user_id", Rows: 1},
+	}
+	if !reflect.DeepEqual(plan.Nodes, want) {
+		t.Errorf("unexpected postgres plan: %+v", plan.Nodes)
+	}
+
+	rows, err = db.Query(`SELECT 1 AS id, 'SIMPLE' AS select_type, 'users' AS "table", 'ALL' AS type, ` +
+		`NULL AS "key", '42' AS rows, 'Using where' AS Extra`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	plan = &QueryPlan{}
+	if err := parseMySQLPlan(rows, plan); err != nil {
+		t.Fatalf("parseMySQLPlan failed: %v", err)
+	}
+	rows.Close()
+	if len(plan.Nodes) != 1 || plan.Nodes[0].Rows != 42 || !plan.Nodes[0].FullScan || plan.Nodes[0].Table != "users" {
+		t.Errorf("unexpected mysql plan: %+v", plan.Nodes)
+	}
+
+	rows, err = db.Query("SELECT ?", "-> Nested loop inner join  (actual time=0.1..0.2 rows=3 loops=1)\n"+
+		"    -> Table scan on users  (cost=0.55 rows=3) (actual time=0.05..0.06 rows=3 loops=1)\n"+
+		"    -> Index lookup on orders using orders_user_id (user_id=users.id)  (actual time=0.01..0.01 rows=1 loops=3)\n")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	plan = &QueryPlan{}
+	if err := parseMySQLAnalyze(rows, plan); err != nil {
+		t.Fatalf("parseMySQLAnalyze failed: %v", err)
+	}
+	rows.Close()
+	if len(plan.Nodes) != 3 || plan.Nodes[1].Depth != 1 || !plan.Nodes[1].FullScan || plan.Nodes[1].Rows != 3 ||
+		plan.Nodes[2].Index != "orders_user_id" || plan.Nodes[2].Table != "orders" {
+		t.Errorf("unexpected mysql analyze plan: %+v", plan.Nodes)
+	}
+}
+
+func TestSlowQueryPlanCapture(t *testing.T) {
+	setupDirtyTestDB(t)
+	pm := NewPerformanceMetrics(10 * time.Millisecond)
+
+	pm.RecordStatement("SELECT * FROM articles WHERE title = ?", []interface{}{"Go"}, time.Second)
+	if len(pm.SlowQueryLog()) != 0 {
+		t.Errorf("expected no plans without capture")
+	}
+
+	pm.CapturePlans(true)
+	pm.RecordStatement("SELECT * FROM articles WHERE id = ?", []interface{}{1}, time.Millisecond)
+	pm.RecordStatement("SELECT * FROM articles WHERE title = ?", []interface{}{"Go"}, time.Second)
+	pm.Flush()
+	log := pm.SlowQueryLog()
+	if len(log) != 1 || log[0].PlanError != nil || log[0].Plan == nil {
+		t.Fatalf("expected one captured plan, got %+v", log)
+	}
+	if !reflect.DeepEqual(log[0].Plan.FullScans(), []string{"articles"}) {
+		t.Errorf("unexpected captured plan: %+v", log[0].Plan.Nodes)
+	}
+	if pm.GetStats()["slow_queries"] != int64(2) {
+		t.Errorf("unexpected stats: %v", pm.GetStats())
+	}
+
+	pm.Reset()
+	if len(pm.SlowQueryLog()) != 0 {
+		t.Errorf("expected Reset to clear captured plans")
+	}
+
+	pm.RecordStatementWithContext(DryRun(context.Background(), NewDryRunCollector()),
+		"SELECT * FROM articles WHERE title = ?", []interface{}{"Go"}, time.Second)
+	pm.Flush()
+	if len(pm.SlowQueryLog()) != 0 {
+		t.Errorf("expected no plans captured in dry-run mode")
+	}
+}
+
+func TestSlowQueryPlanCaptureCanceledContext(t *testing.T) {
+	setupDirtyTestDB(t)
+	pm := NewPerformanceMetrics(0)
+	pm.CapturePlans(true)
+
+	// a request context is typically canceled once the query returns
+	ctx, cancel := context.WithCancel(context.Background())
+	pm.RecordStatementWithContext(ctx, "SELECT * FROM articles", nil, time.Second)
+	cancel()
+	pm.Flush()
+	if log := pm.SlowQueryLog(); len(log) != 1 || log[0].PlanError != nil || log[0].Plan == nil {
+		t.Errorf("expected the plan captured after cancellation, got %+v", log)
+	}
+}
+
+func TestSlowQueryPlanCaptureSingleConnection(t *testing.T) {
+	setupDirtyTestDB(t)
+	db := GetConnection()
+	db.SetMaxOpenConns(1)
+	defer db.SetMaxOpenConns(0)
+
+	pm := NewPerformanceMetrics(0)
+	pm.CapturePlans(true)
+	rows, err := QueryWithContext(context.Background(), "SELECT * FROM articles")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	// the capture waits for the connection the open rows hold
+	pm.RecordStatementWithContext(context.Background(), "SELECT * FROM articles", nil, time.Second)
+	rows.Close()
+	pm.Flush()
+	if log := pm.SlowQueryLog(); len(log) != 1 || log[0].PlanError != nil {
+		t.Errorf("expected one captured plan, got %+v", log)
+	}
+}
@@ -0,0 +1,246 @@
+package activerecord
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Expr is a composable SQL condition. SQL renders it with "?" placeholders
+// and returns its args in placeholder order.
+//
+//	qb.WhereExpr(And(Or(Eq("status", "open"), Gt("priority", 3)), Not(Like("title", "%draft%"))))
+type Expr interface {
+	SQL(d Dialect) (string, []interface{})
+}
+
+// Raw is a condition written as SQL, with "?" placeholders. A *QueryBuilder
+// arg is inlined as a parenthesized subquery.
+func Raw(sql string, args ...interface{}) Expr {
+	return rawExpr{sql: sql, args: args}
+}
+
+type rawExpr struct {
+	sql  string
+	args []interface{}
+}
+
+func (e rawExpr) SQL(d Dialect) (string, []interface{}) {
+	return inlineSubqueries(d, e.sql, e.args)
+}
+
+// Eq is column = value, or column IS NULL for a nil value. Like the other
+// comparisons, it accepts a *QueryBuilder value as a scalar subquery.
+func Eq(column string, value interface{}) Expr { return compareExpr{column, "=", value} }
+
+// Neq is column <> value, or column IS NOT NULL for a nil value.
+func Neq(column string, value interface{}) Expr { return compareExpr{column, "<>", value} }
+
+// Gt is column > value.
+func Gt(column string, value interface{}) Expr { return compareExpr{column, ">", value} }
+
+// Gte is column >= value.
+func Gte(column string, value interface{}) Expr { return compareExpr{column, ">=", value} }
+
+// Lt is column < value.
+func Lt(column string, value interface{}) Expr { return compareExpr{column, "<", value} }
+
+// Lte is column <= value.
+func Lte(column string, value interface{}) Expr { return compareExpr{column, "<=", value} }
+
+// Like is column LIKE pattern.
+func Like(column string, pattern interface{}) Expr { return compareExpr{column, "LIKE", pattern} }
+
+type compareExpr struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (e compareExpr) SQL(d Dialect) (string, []interface{}) {
+	column := quoteIdentifier(d, e.column)
+	if e.value == nil {
+		switch e.op {
+		case "=":
+			return column + " IS NULL", nil
+		case "<>":
+			return column + " IS NOT NULL", nil
+		}
+	}
+	if sub, ok := asSubquery(e.value); ok {
+		sql, args := sub.SQL(d)
+		return column + " " + e.op + " " + sql, args
+	}
+	return column + " " + e.op + " ?", []interface{}{e.value}
+}
+
+// In is column IN (values...). A single slice argument is expanded and a
+// single *QueryBuilder is used as a subquery; an empty list matches nothing.
+func In(column string, values ...interface{}) Expr {
+	if len(values) == 1 {
+		v := reflect.ValueOf(values[0])
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+			values = make([]interface{}, v.Len())
+			for i := range values {
+				values[i] = v.Index(i).Interface()
+			}
+		}
+	}
+	return inExpr{column: column, values: values}
+}
+
+type inExpr struct {
+	column string
+	values []interface{}
+}
+
+func (e inExpr) SQL(d Dialect) (string, []interface{}) {
+	if len(e.values) == 0 {
+		return "1 = 0", nil
+	}
+	if len(e.values) == 1 {
+		if sub, ok := asSubquery(e.values[0]); ok {
+			sql, args := sub.SQL(d)
+			return quoteIdentifier(d, e.column) + " IN " + sql, args
+		}
+	}
+	return quoteIdentifier(d, e.column) + " IN (" + placeholders(len(e.values)) + ")", e.values
+}
+
+// Between is column BETWEEN low AND high.
+func Between(column string, low, high interface{}) Expr {
+	return betweenExpr{column: column, low: low, high: high}
+}
+
+type betweenExpr struct {
+	column    string
+	low, high interface{}
+}
+
+func (e betweenExpr) SQL(d Dialect) (string, []interface{}) {
+	return quoteIdentifier(d, e.column) + " BETWEEN ? AND ?", []interface{}{e.low, e.high}
+}
+
+// And matches when all expressions match. Nil and empty expressions are
+// skipped.
+func And(exprs ...Expr) Expr { return junction{op: "AND", exprs: exprs} }
+
+// Or matches when any expression matches. Nil and empty expressions are
+// skipped.
+func Or(exprs ...Expr) Expr { return junction{op: "OR", exprs: exprs} }
+
+type junction struct {
+	op    string
+	exprs []Expr
+}
+
+func (e junction) SQL(d Dialect) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+	for _, expr := range e.exprs {
+		if expr == nil {
+			continue
+		}
+		sql, exprArgs := expr.SQL(d)
+		if sql == "" {
+			continue
+		}
+		if len(e.exprs) > 1 && hasTopLevelLogic(sql) {
+			sql = "(" + sql + ")"
+		}
+		parts = append(parts, sql)
+		args = append(args, exprArgs...)
+	}
+	return strings.Join(parts, " "+e.op+" "), args
+}
+
+// Not negates an expression.
+func Not(expr Expr) Expr { return notExpr{expr} }
+
+type notExpr struct {
+	expr Expr
+}
+
+func (e notExpr) SQL(d Dialect) (string, []interface{}) {
+	if e.expr == nil {
+		return "", nil
+	}
+	sql, args := e.expr.SQL(d)
+	if sql == "" {
+		return "", nil
+	}
+	return "NOT (" + sql + ")", args
+}
+
+// Conditions is the AND of column = value for each entry, in column order.
+func Conditions(conditions map[string]interface{}) Expr {
+	columns := make([]string, 0, len(conditions))
+	for column := range conditions {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	exprs := make([]Expr, len(columns))
+	for i, column := range columns {
+		exprs[i] = Eq(column, conditions[column])
+	}
+	return And(exprs...)
+}
+
+// joinExprs renders expressions joined by sep.
+func joinExprs(d Dialect, exprs []Expr, sep string) (string, []interface{}) {
+	parts := make([]string, len(exprs))
+	var args []interface{}
+	for i, expr := range exprs {
+		sql, exprArgs := expr.SQL(d)
+		parts[i] = sql
+		args = append(args, exprArgs...)
+	}
+	return strings.Join(parts, sep), args
+}
+
+// hasTopLevelLogic reports whether sql contains AND, OR or || outside of
+// parentheses and quotes, so it must be grouped when combined.
+func hasTopLevelLogic(sql string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0:
+			if c == '|' && i+1 < len(sql) && sql[i+1] == '|' {
+				return true
+			}
+			if isKeywordAt(sql, i, "AND") || isKeywordAt(sql, i, "OR") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isKeywordAt reports whether the keyword starts at sql[i] as a whole word.
+func isKeywordAt(sql string, i int, keyword string) bool {
+	end := i + len(keyword)
+	if end > len(sql) || !strings.EqualFold(sql[i:end], keyword) {
+		return false
+	}
+	if i > 0 && isWordByte(sql[i-1]) {
+		return false
+	}
+	return end == len(sql) || !isWordByte(sql[end])
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
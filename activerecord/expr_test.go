@@ -0,0 +1,89 @@
+package activerecord
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExprSQL(t *testing.T) {
+	d := PostgresDialect{}
+	tests := []struct {
+		expr Expr
+		want string
+		args []interface{}
+	}{
+		{Eq("name", "bob"), `"name" = ?`, []interface{}{"bob"}},
+		{Eq("deleted_at", nil), `"deleted_at" IS NULL`, nil},
+		{Neq("deleted_at", nil), `"deleted_at" IS NOT NULL`, nil},
+		{Gte("u.age", 18), `"u"."age" >= ?`, []interface{}{18}},
+		{In("id", []int{1, 2}), `"id" IN (?, ?)`, []interface{}{1, 2}},
+		{In("id"), `1 = 0`, nil},
+		{Between("age", 18, 30), `"age" BETWEEN ? AND ?`, []interface{}{18, 30}},
+		{
+			And(Or(Eq("a", 1), Eq("b", 2)), Not(Raw("c"))),
+			`("a" = ? OR "b" = ?) AND NOT (c)`,
+			[]interface{}{1, 2},
+		},
+		{
+			Or(And(Lt("a", 1), Like("b", "x%")), Raw("c = ? OR d = ?", 3, 4)),
+			`("a" < ? AND "b" LIKE ?) OR (c = ? OR d = ?)`,
+			[]interface{}{1, "x%", 3, 4},
+		},
+		{And(nil, Raw(""), Raw("(a OR b)")), `(a OR b)`, nil},
+		{Conditions(map[string]interface{}{"b": 2, "a": 1}), `"a" = ? AND "b" = ?`, []interface{}{1, 2}},
+	}
+	for _, tt := range tests {
+		got, args := tt.expr.SQL(d)
+		if got != tt.want {
+			t.Errorf("unexpected SQL:\n got: %s\nwant: %s", got, tt.want)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: unexpected args %v, want %v", got, args, tt.args)
+		}
+	}
+}
+
+func TestQueryBuilderConditionGroups(t *testing.T) {
+	qb := NewQueryBuilder("users").SetDialect(PostgresDialect{})
+	qb.WhereGroup(func(g *QueryBuilder) {
+		g.Where("a = ?", 1).OrWhere("b = ?", 2)
+	}).WhereNot("c = ?", 3).
+		GroupBy("team").
+		Having("COUNT(*) > ?", 4).
+		WhereExpr(In("role", "admin", "owner"))
+
+	query, args := qb.Build()
+	want := `SELECT * FROM "users" WHERE (a = $1 OR b = $2) AND NOT (c = $3) AND "role" IN ($4, $5) GROUP BY "team" HAVING COUNT(*) > $6`
+	if query != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", query, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, 3, "admin", "owner", 4}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	or, _ := NewQueryBuilder("users").SetDialect(PostgresDialect{}).
+		Where("a = ?", 1).Where("b = ?", 2).OrWhere("c = ?", 3).Build()
+	if or != `SELECT * FROM "users" WHERE (a = $1 AND b = $2) OR c = $3` {
+		t.Errorf("unexpected OrWhere query: %s", or)
+	}
+}
+
+func TestQueryBuilderExprFind(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"Go", "Rust", "Zig"} {
+		if err := Create(&article{Title: title, Views: i * 10}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var articles []*article
+	err := NewQueryBuilder("articles").
+		WhereExpr(Or(Eq("title", "Go"), Gt("views", 15)), Not(Eq("title", "Zig"))).
+		Find(&articles)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(articles) != 1 || articles[0].Title != "Go" {
+		t.Errorf("unexpected articles: %+v", articles)
+	}
+}
@@ -1,6 +1,7 @@
 package activerecord
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -509,10 +510,13 @@ func testQueryBuilder(t *testing.T) {
 	}
 
 	// Test dry run mode
-	qb.SetMode(DryRunMode)
-	err = qb.Find(&youngUsers)
-	if err != nil {
-		t.Fatalf("Dry run should not fail: %v", err)
+	collector := NewDryRunCollector()
+	err = qb.DryRun(collector).Find(&youngUsers)
+	if !errors.Is(err, ErrDryRun) {
+		t.Fatalf("Dry run should return ErrDryRun, got %v", err)
+	}
+	if len(collector.Statements()) != 1 {
+		t.Errorf("Dry run should record the query, got %v", collector.SQL())
 	}
 }
 
@@ -113,7 +113,7 @@ func (m *HookableModel) Create() error {
 		return err
 	}
 
-	if err := Create(m); err != nil {
+	if err := Create(m.target(m)); err != nil {
 		return err
 	}
 
@@ -126,7 +126,7 @@ func (m *HookableModel) Update() error {
 		return err
 	}
 
-	if err := Update(m); err != nil {
+	if err := Update(m.target(m)); err != nil {
 		return err
 	}
 
@@ -159,7 +159,7 @@ func (m *HookableModel) Delete() error {
 		return err
 	}
 
-	if err := Delete(m); err != nil {
+	if err := Delete(m.target(m)); err != nil {
 		return err
 	}
 
@@ -172,7 +172,7 @@ func (m *HookableModel) Find(id interface{}) error {
 		return err
 	}
 
-	if err := Find(m, id); err != nil {
+	if err := Find(m.target(m), id); err != nil {
 		return err
 	}
 
@@ -0,0 +1,256 @@
+package activerecord
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidIdentifier is returned for table, column and alias names that
+// are not plain, optionally qualified, identifiers and for columns the model
+// does not have. Intentional SQL is written with Raw and the *Expr methods.
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+// ErrInvalidDirection is returned for sort directions other than ASC and
+// DESC, optionally followed by NULLS FIRST or NULLS LAST.
+var ErrInvalidDirection = errors.New("invalid sort direction")
+
+// isIdentifier reports whether name is a plain identifier, optionally
+// qualified as "table.column" or "table.*".
+func isIdentifier(name string) bool {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "*" && i == len(parts)-1 && i > 0 {
+			continue
+		}
+		if !isPlainIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkIdentifier(name string) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+	}
+	return nil
+}
+
+// checkColumns reports keys of values that are not columns of the model.
+func (s *ModelSchema) checkColumns(values map[string]interface{}) error {
+	for column := range values {
+		if err := s.checkColumn(column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkColumn reports a name that is not a column of the model.
+func (s *ModelSchema) checkColumn(column string) error {
+	if err := checkIdentifier(column); err != nil {
+		return err
+	}
+	if _, ok := s.FieldByColumn(unqualified(column)); !ok {
+		return fmt.Errorf("%w: %s has no column %s", ErrInvalidIdentifier, s.Type.Name(), column)
+	}
+	return nil
+}
+
+// columnExpr is a column, or "*", optionally aliased.
+type columnExpr struct {
+	name  string
+	alias string
+}
+
+func (e columnExpr) SQL(d Dialect) (string, []interface{}) {
+	sql := quoteIdentifier(d, e.name)
+	if e.alias != "" {
+		sql += " AS " + d.QuoteIdentifier(e.alias)
+	}
+	return sql, nil
+}
+
+// sqlKeywords are words that start an expression rather than name a column
+// or an alias in a select list.
+var sqlKeywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "BETWEEN": true, "CASE": true, "CAST": true,
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true, "DISTINCT": true,
+	"ELSE": true, "END": true, "EXISTS": true, "FALSE": true, "FROM": true, "IN": true,
+	"INTERVAL": true, "IS": true, "LIKE": true, "NOT": true, "NULL": true, "OR": true,
+	"SELECT": true, "THEN": true, "TOP": true, "TRUE": true, "WHEN": true, "WHERE": true,
+}
+
+// selectColumn parses a select field that is a bare or qualified column,
+// or "*", with an optional alias, none of them a keyword. It reports false
+// for anything else, which is an expression.
+func selectColumn(s string) (columnExpr, bool) {
+	c, err := parseColumn(s)
+	if err != nil || sqlKeywords[strings.ToUpper(c.alias)] {
+		return c, false
+	}
+	for _, part := range strings.Split(c.name, ".") {
+		if sqlKeywords[strings.ToUpper(part)] {
+			return c, false
+		}
+	}
+	return c, true
+}
+
+// parseColumn parses a column or table name optionally followed by an
+// alias, as in "users.name", "name AS author" or "users u".
+func parseColumn(s string) (columnExpr, error) {
+	fields := strings.Fields(s)
+	var c columnExpr
+	switch {
+	case len(fields) == 1:
+		c = columnExpr{name: fields[0]}
+	case len(fields) == 2:
+		c = columnExpr{name: fields[0], alias: fields[1]}
+	case len(fields) == 3 && strings.EqualFold(fields[1], "AS"):
+		c = columnExpr{name: fields[0], alias: fields[2]}
+	default:
+		return c, fmt.Errorf("%w: %q", ErrInvalidIdentifier, s)
+	}
+	if c.name == "*" && c.alias == "" {
+		return c, nil
+	}
+	if !isIdentifier(c.name) || (c.alias != "" && !isPlainIdentifier(c.alias)) {
+		return c, fmt.Errorf("%w: %q", ErrInvalidIdentifier, s)
+	}
+	return c, nil
+}
+
+// orderExpr is a column with a sort direction.
+type orderExpr struct {
+	column string
+	desc   bool
+	nulls  string // FIRST, LAST or empty for the database default
+}
+
+func (e orderExpr) SQL(d Dialect) (string, []interface{}) {
+	column := quoteIdentifier(d, e.column)
+	direction := " ASC"
+	if e.desc {
+		direction = " DESC"
+	}
+	switch {
+	case e.nulls == "":
+		return column + direction, nil
+	case d.Name() == "mysql":
+		// MySQL has no NULLS FIRST/LAST, so sort on IS NULL first
+		nulls := " DESC"
+		if e.nulls == "LAST" {
+			nulls = " ASC"
+		}
+		return column + " IS NULL" + nulls + ", " + column + direction, nil
+	}
+	return column + direction + " NULLS " + e.nulls, nil
+}
+
+// parseDirection parses ASC or DESC, case-insensitively and optionally
+// followed by NULLS FIRST or NULLS LAST. An empty direction is ASC.
+func parseDirection(direction string) (desc bool, nulls string, err error) {
+	words := strings.Fields(strings.ToUpper(direction))
+	if len(words) == 0 {
+		return false, "", nil
+	}
+	if (words[0] != "ASC" && words[0] != "DESC") || (len(words) != 1 && len(words) != 3) {
+		return false, "", fmt.Errorf("%w: %q", ErrInvalidDirection, direction)
+	}
+	if len(words) == 3 {
+		if words[1] != "NULLS" || (words[2] != "FIRST" && words[2] != "LAST") {
+			return false, "", fmt.Errorf("%w: %q", ErrInvalidDirection, direction)
+		}
+		nulls = words[2]
+	}
+	return words[0] == "DESC", nulls, nil
+}
+
+// fail records the first error of a builder method, returned when the query
+// is executed.
+func (qb *QueryBuilder) fail(err error) *QueryBuilder {
+	if qb.err == nil {
+		qb.err = err
+	}
+	return qb
+}
+
+// validate reports errors recorded while building the query or applying
+// its default scope, an invalid table name, an invalid cursor and columns
+// the model does not have, in the query and in its subqueries.
+func (qb *QueryBuilder) validate() error {
+	qb = qb.withDefaultScope()
+	if qb.err != nil {
+		return qb.err
+	}
+	if err := checkIdentifier(qb.tableName); err != nil {
+		return err
+	}
+	if err := qb.checkCursor(); err != nil {
+		return err
+	}
+	if err := qb.checkModelColumns(); err != nil {
+		return err
+	}
+	for _, q := range qb.nestedQueries() {
+		if err := q.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkModelColumns reports selected, grouped, ordered and keyset columns
+// of the query's table that its model does not have. Only models whose
+// table is queried are checked, and unqualified columns only when no other
+// table is involved.
+func (qb *QueryBuilder) checkModelColumns() error {
+	if qb.model == nil {
+		return nil
+	}
+	modeler, ok := reflect.New(qb.model).Interface().(Modeler)
+	if !ok || modeler.TableName() != qb.tableName {
+		return nil
+	}
+	schema := schemaFor(qb.model)
+
+	aliases := make(map[string]bool)
+	var columns []string
+	for _, e := range qb.selectFields {
+		if c, ok := e.(columnExpr); ok {
+			columns = append(columns, c.name)
+			aliases[c.alias] = c.alias != ""
+		}
+	}
+	for _, e := range qb.groupBy {
+		if c, ok := e.(columnExpr); ok {
+			columns = append(columns, c.name)
+		}
+	}
+	for _, e := range qb.orderBy {
+		if o, ok := e.(orderExpr); ok {
+			columns = append(columns, o.column)
+		}
+	}
+	for _, k := range qb.keyset {
+		columns = append(columns, k.column)
+	}
+
+	joined := len(qb.joins) > 0 || len(qb.ctes) > 0 || len(qb.compounds) > 0
+	for _, column := range columns {
+		table, name := "", column
+		if i := strings.LastIndexByte(column, '.'); i >= 0 {
+			table, name = column[:i], column[i+1:]
+		}
+		if name == "*" || aliases[column] || (table == "" && joined) || (table != "" && table != qb.tableName) {
+			continue
+		}
+		if _, ok := schema.FieldByColumn(name); !ok {
+			return fmt.Errorf("%w: %s has no column %s", ErrInvalidIdentifier, qb.model.Name(), name)
+		}
+	}
+	return nil
+}
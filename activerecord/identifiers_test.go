@@ -0,0 +1,119 @@
+package activerecord
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIdentifierQuoting(t *testing.T) {
+	pg := PostgresDialect{}
+	query, _ := NewQueryBuilder("users").SetDialect(pg).
+		Select("users.id", "name AS author", "teams.*").
+		Join("teams t", "t.id = users.team_id").
+		GroupBy("users.id").
+		OrderBy("name", "desc nulls last").
+		OrderByExpr(Raw("LENGTH(name)")).
+		Build()
+	want := `SELECT "users"."id", "name" AS "author", "teams".* FROM "users" JOIN "teams" AS "t" ON t.id = users.team_id ` +
+		`GROUP BY "users"."id" ORDER BY "name" DESC NULLS LAST, LENGTH(name)`
+	if query != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", query, want)
+	}
+
+	mysql, _ := NewQueryBuilder("users").SetDialect(MySQLDialect{}).OrderBy("name", "ASC NULLS FIRST").Build()
+	if mysql != "SELECT * FROM `users` ORDER BY `name` IS NULL DESC, `name` ASC" {
+		t.Errorf("unexpected mysql query: %s", mysql)
+	}
+
+	// Fields that are not plain columns are selected as written
+	for field, want := range map[string]string{
+		"1":                     `SELECT 1 FROM "users"`,
+		"DISTINCT name":         `SELECT DISTINCT name FROM "users"`,
+		"COUNT(*)":              `SELECT COUNT(*) FROM "users"`,
+		"name AS select":        `SELECT name AS select FROM "users"`,
+		"users.name AS author":  `SELECT "users"."name" AS "author" FROM "users"`,
+		"SUM(age) AS total_age": `SELECT SUM(age) AS total_age FROM "users"`,
+	} {
+		if got, _ := NewQueryBuilder("users").SetDialect(pg).Select(field).Build(); got != want {
+			t.Errorf("Select(%q):\n got: %s\nwant: %s", field, got, want)
+		}
+	}
+
+	if got := quoteTable(pg, `users"; DROP TABLE users; --`); got != `"users""; DROP TABLE users; --"` {
+		t.Errorf("unexpected quoted table: %s", got)
+	}
+}
+
+func TestIdentifierGuardrails(t *testing.T) {
+	setupDirtyTestDB(t)
+	if err := Create(&article{Title: "a"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		qb   *QueryBuilder
+		want error
+	}{
+		{"order column", NewQueryBuilder("articles").OrderBy("title; DROP TABLE articles", "asc"), ErrInvalidIdentifier},
+		{"order direction", NewQueryBuilder("articles").OrderBy("title", "asc, (SELECT 1)"), ErrInvalidDirection},
+		{"select", NewQueryBuilder("articles").Select("title", "password"), ErrInvalidIdentifier},
+		{"select distinct", NewQueryBuilder("articles").Select("DISTINCT title"), nil},
+		{"select literal", NewQueryBuilder("articles").Select("1"), nil},
+		{"select expression", NewQueryBuilder("articles").Select("title", "SUM(views) AS total").GroupBy("title"), nil},
+		{"group by", NewQueryBuilder("articles").GroupBy("1 OR 1"), ErrInvalidIdentifier},
+		{"join", NewQueryBuilder("articles").Join("users ON 1=1 --", "true"), ErrInvalidIdentifier},
+		{"table", NewQueryBuilder("articles a, users"), ErrInvalidIdentifier},
+		{"keyset", NewQueryBuilder("articles").Keyset("id DESC NULLS LAST"), ErrInvalidDirection},
+		{"model column", NewQueryBuilder("articles").OrderBy("password", "asc"), ErrInvalidIdentifier},
+		{"subquery order", NewQueryBuilder("articles").WhereIn("id", []interface{}{
+			NewQueryBuilder("articles").Select("id").OrderBy("views; DROP TABLE articles", "asc").Limit(1)}), ErrInvalidIdentifier},
+		{"scalar subquery", NewQueryBuilder("articles").SelectQuery(NewQueryBuilder("articles").Select("MAX(views)").OrderBy("views", "sideways"), "top"), ErrInvalidDirection},
+		{"join subquery", NewQueryBuilder("articles").JoinQuery(NewQueryBuilder("articles").GroupBy("1 OR 1"), "a2", "a2.id = articles.id"), ErrInvalidIdentifier},
+		{"cte", NewQueryBuilder("articles").With("recent", NewQueryBuilder("articles a, users")), ErrInvalidIdentifier},
+		{"compound", NewQueryBuilder("articles").Union(NewQueryBuilder("articles").OrderBy("title; --", "asc")), ErrInvalidIdentifier},
+		{"condition subquery", NewQueryBuilder("articles").WhereExpr(Not(In("id", NewQueryBuilder("articles").Select("id").OrderBy("x y", "asc")))), ErrInvalidIdentifier},
+		{"valid", NewQueryBuilder("articles").OrderBy("articles.views", "DESC").OrderBy("title", ""), nil},
+	}
+	for _, tt := range tests {
+		var articles []*article
+		if err := tt.qb.Find(&articles); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Unknown columns are allowed when other tables may provide them
+	var articles []*article
+	err := NewQueryBuilder("articles").LeftJoin("articles other", "other.id = articles.id").
+		OrderBy("other.views", "asc").Find(&articles)
+	if err != nil {
+		t.Errorf("unexpected error for a joined column: %v", err)
+	}
+
+	if _, err := NewQueryBuilder("articles").Sum("views) FROM articles; --"); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("expected ErrInvalidIdentifier from Sum, got %v", err)
+	}
+	if _, err := NewQueryBuilder("articles").UpdateAll(map[string]interface{}{"title = 'x', body": "y"}); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("expected ErrInvalidIdentifier from UpdateAll, got %v", err)
+	}
+	if _, err := BulkUpdate(article{}, map[string]interface{}{"1=1 OR id": 1}, map[string]interface{}{"title": "x"}); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("expected ErrInvalidIdentifier from BulkUpdate, got %v", err)
+	}
+	if err := FindOrCreate(&article{}, map[string]interface{}{"secret": 1}); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("expected ErrInvalidIdentifier from FindOrCreate, got %v", err)
+	}
+	if _, err := BatchUpsert([]interface{}{&article{Title: "b"}}, []string{"id"}, []string{"title = 'x', views"}); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("expected ErrInvalidIdentifier from BatchUpsert, got %v", err)
+	}
+	if _, err := BatchUpsert([]interface{}{&article{Title: "b"}}, []string{"secret"}, nil); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("expected ErrInvalidIdentifier from BatchUpsert, got %v", err)
+	}
+	a := &article{Title: "c"}
+	a.SetID(int64(1))
+	if err := UpdateWithSQLExpr(a, map[string]string{"views = 0, title": "'x'"}); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("expected ErrInvalidIdentifier from UpdateWithSQLExpr, got %v", err)
+	}
+	if err := UpdateWithSQLExpr(a, map[string]string{"views": "views + ?"}, 5); err != nil {
+		t.Errorf("UpdateWithSQLExpr failed: %v", err)
+	}
+}
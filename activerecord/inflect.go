@@ -0,0 +1,76 @@
+package activerecord
+
+import "strings"
+
+// irregularPlurals maps irregular and uncountable plural nouns to their
+// singular.
+var irregularPlurals = map[string]string{
+	"people":    "person",
+	"men":       "man",
+	"women":     "woman",
+	"children":  "child",
+	"mice":      "mouse",
+	"geese":     "goose",
+	"feet":      "foot",
+	"teeth":     "tooth",
+	"oxen":      "ox",
+	"indices":   "index",
+	"matrices":  "matrix",
+	"vertices":  "vertex",
+	"analyses":  "analysis",
+	"crises":    "crisis",
+	"theses":    "thesis",
+	"criteria":  "criterion",
+	"phenomena": "phenomenon",
+	"wives":     "wife",
+	"knives":    "knife",
+	"lives":     "life",
+	"leaves":    "leaf",
+	"wolves":    "wolf",
+	"halves":    "half",
+	"shelves":   "shelf",
+	"heroes":    "hero",
+	"potatoes":  "potato",
+	"tomatoes":  "tomato",
+	"echoes":    "echo",
+	"quizzes":   "quiz",
+	"movies":    "movie",
+	"news":      "news",
+	"series":    "series",
+	"species":   "species",
+	"sheep":     "sheep",
+	"fish":      "fish",
+	"data":      "data",
+}
+
+// singularize returns the singular of a plural English noun, or false when
+// the word is not recognized as a plural. In a snake_case name only the last
+// word is singularized.
+func singularize(name string) (string, bool) {
+	prefix, word := "", name
+	if i := strings.LastIndexByte(name, '_'); i >= 0 {
+		prefix, word = name[:i+1], name[i+1:]
+	}
+	lower := strings.ToLower(word)
+	if singular, ok := irregularPlurals[lower]; ok {
+		return prefix + singular, true
+	}
+
+	var singular string
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		singular = word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "shes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "xes"),
+		strings.HasSuffix(lower, "zzes"), strings.HasSuffix(lower, "uses"):
+		singular = word[:len(word)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		// singular already: address, status, analysis
+		return "", false
+	case strings.HasSuffix(lower, "s") && len(lower) > 1:
+		singular = word[:len(word)-1]
+	default:
+		return "", false
+	}
+	return prefix + singular, true
+}
@@ -0,0 +1,29 @@
+package activerecord
+
+import "testing"
+
+func TestSingularize(t *testing.T) {
+	tests := map[string]string{
+		"users":           "user",
+		"categories":      "category",
+		"addresses":       "address",
+		"statuses":        "status",
+		"boxes":           "box",
+		"matches":         "match",
+		"wishes":          "wish",
+		"people":          "person",
+		"movies":          "movie",
+		"archives":        "archive",
+		"user_categories": "user_category",
+	}
+	for plural, want := range tests {
+		if got, ok := singularize(plural); !ok || got != want {
+			t.Errorf("singularize(%q) = %q, %v; want %q", plural, got, ok, want)
+		}
+	}
+	for _, word := range []string{"status", "address", "analysis", "person", ""} {
+		if got, ok := singularize(word); ok {
+			t.Errorf("expected %q not to be recognized as a plural, got %q", word, got)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package activerecord
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// PrimaryKeyer lets a model declare its primary key column without tags.
+type PrimaryKeyer interface {
+	PrimaryKey() string
+}
+
+// CompositeKeyer lets a model declare a multi-column primary key without
+// tags.
+type CompositeKeyer interface {
+	PrimaryKeys() []string
+}
+
+// CompositeKey is a primary key value made of several columns, in key
+// column order.
+//
+//	Find(&membership, CompositeKey{groupID, userID})
+type CompositeKey []interface{}
+
+// KeyGenerator produces a new primary key value.
+type KeyGenerator func() (interface{}, error)
+
+var (
+	keyGeneratorsMu sync.RWMutex
+	keyGenerators   = map[string]KeyGenerator{
+		"uuidv4":    func() (interface{}, error) { return NewUUIDv4() },
+		"uuidv7":    func() (interface{}, error) { return NewUUIDv7() },
+		"ulid":      func() (interface{}, error) { return NewULID() },
+		"snowflake": func() (interface{}, error) { return NextSnowflakeID() },
+	}
+)
+
+// RegisterKeyGenerator registers a key generator usable as
+// `db:"id,pk,generate=<name>"`.
+func RegisterKeyGenerator(name string, gen KeyGenerator) {
+	keyGeneratorsMu.Lock()
+	defer keyGeneratorsMu.Unlock()
+	keyGenerators[name] = gen
+}
+
+func lookupKeyGenerator(name string) (KeyGenerator, bool) {
+	keyGeneratorsMu.RLock()
+	defer keyGeneratorsMu.RUnlock()
+	gen, ok := keyGenerators[name]
+	return gen, ok
+}
+
+// generateKey fills an empty primary key using the generator named by its
+// `generate` tag option.
+func (s *ModelSchema) generateKey(v reflect.Value) error {
+	pk := s.PrimaryKey
+	if pk == nil {
+		return nil
+	}
+	name := pk.Options["generate"]
+	if name == "" {
+		return nil
+	}
+	field, ok := fieldByIndex(v, pk.Index, true)
+	if !ok || !field.IsZero() {
+		return nil
+	}
+	gen, ok := lookupKeyGenerator(name)
+	if !ok {
+		return fmt.Errorf("unknown key generator %q", name)
+	}
+	key, err := gen()
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	return assignValue(GetDialect(), field, key)
+}
+
+// isBlankKey reports whether a primary key value is unset.
+func isBlankKey(id interface{}) bool {
+	if id == nil {
+		return true
+	}
+	if key, ok := id.(CompositeKey); ok {
+		for _, part := range key {
+			if !isBlankKey(part) {
+				return false
+			}
+		}
+		return true
+	}
+	v := reflect.ValueOf(id)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.IsZero()
+}
+
+// NewUUIDv4 returns a random (version 4) UUID.
+func NewUUIDv4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u), nil
+}
+
+// NewUUIDv7 returns a time-ordered (version 7) UUID.
+func NewUUIDv7() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return "", err
+	}
+	ms := uint64(time.Now().UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	u[6] = (u[6] & 0x0f) | 0x70
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u), nil
+}
+
+func formatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a lexicographically sortable ULID.
+func NewULID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return "", err
+	}
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint16(u[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(u[2:6], uint32(ms))
+
+	// 128 bits encode to 26 base32 characters, most significant first.
+	hi := binary.BigEndian.Uint64(u[0:8])
+	lo := binary.BigEndian.Uint64(u[8:16])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+// snowflakeEpoch is the custom epoch of snowflake IDs (2020-01-01 UTC).
+const snowflakeEpoch int64 = 1577836800000
+
+var snowflake = struct {
+	sync.Mutex
+	node   int64
+	lastMs int64
+	seq    int64
+}{}
+
+// SetSnowflakeNode sets the node ID (0-1023) embedded in snowflake IDs.
+func SetSnowflakeNode(node int64) error {
+	if node < 0 || node > 1023 {
+		return fmt.Errorf("snowflake node must be between 0 and 1023, got %d", node)
+	}
+	snowflake.Lock()
+	defer snowflake.Unlock()
+	snowflake.node = node
+	return nil
+}
+
+// NextSnowflakeID returns a 64-bit, time-ordered snowflake ID made of a
+// 41-bit millisecond timestamp, a 10-bit node ID and a 12-bit sequence.
+func NextSnowflakeID() (int64, error) {
+	snowflake.Lock()
+	defer snowflake.Unlock()
+
+	ms := time.Now().UnixMilli() - snowflakeEpoch
+	if ms < snowflake.lastMs {
+		ms = snowflake.lastMs
+	}
+	if ms == snowflake.lastMs {
+		snowflake.seq = (snowflake.seq + 1) & 0xfff
+		if snowflake.seq == 0 {
+			// Sequence exhausted for this millisecond; wait for the next one.
+			for ms <= snowflake.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				ms = time.Now().UnixMilli() - snowflakeEpoch
+			}
+		}
+	} else {
+		snowflake.seq = 0
+	}
+	snowflake.lastMs = ms
+	return ms<<22 | snowflake.node<<12 | snowflake.seq, nil
+}
@@ -0,0 +1,142 @@
+package activerecord
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type apiToken struct {
+	Token     string    `db:"token,pk,generate=uuidv7"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (m *apiToken) TableName() string        { return "api_tokens" }
+func (m *apiToken) GetID() interface{}       { return m.Token }
+func (m *apiToken) SetID(id interface{})     { m.Token, _ = id.(string) }
+func (m *apiToken) GetCreatedAt() time.Time  { return m.CreatedAt }
+func (m *apiToken) SetCreatedAt(t time.Time) { m.CreatedAt = t }
+func (m *apiToken) GetUpdatedAt() time.Time  { return m.UpdatedAt }
+func (m *apiToken) SetUpdatedAt(t time.Time) { m.UpdatedAt = t }
+func (m *apiToken) Find(id interface{}) error {
+	return Find(m, id)
+}
+
+type legacyAccount struct {
+	BaseModel
+	Code string `db:"account_code"`
+}
+
+func (m *legacyAccount) PrimaryKey() string { return "account_code" }
+
+func TestPrimaryKeyTag(t *testing.T) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	if _, err := db.Exec(`CREATE TABLE api_tokens (
+		token TEXT PRIMARY KEY,
+		name TEXT,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	tok := &apiToken{Name: "deploy"}
+	if err := Create(tok); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(tok.Token) != 36 || tok.Token[14] != '7' {
+		t.Fatalf("expected generated UUIDv7 key, got %q", tok.Token)
+	}
+
+	tok.Name = "deploy-prod"
+	if err := Update(tok); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var found apiToken
+	if err := Find(&found, tok.Token); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if found.Name != "deploy-prod" {
+		t.Errorf("expected updated name, got %q", found.Name)
+	}
+
+	if err := Delete(tok); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := Find(&found, tok.Token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	explicit := &apiToken{Token: "fixed", Name: "manual"}
+	if err := Create(explicit); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if explicit.Token != "fixed" {
+		t.Errorf("explicit key should be kept, got %q", explicit.Token)
+	}
+}
+
+func TestPrimaryKeyer(t *testing.T) {
+	if got := primaryKeyColumns(&legacyAccount{}); !reflect.DeepEqual(got, []string{"account_code"}) {
+		t.Errorf("expected account_code, got %v", got)
+	}
+	if got := primaryKeyColumns(&ModelTest{}); !reflect.DeepEqual(got, []string{"id"}) {
+		t.Errorf("expected id, got %v", got)
+	}
+	columns, values := primaryKey(&legacyAccount{Code: "ACME"})
+	if columns[0] != "account_code" || values[0] != "ACME" {
+		t.Errorf("unexpected primary key %v = %v", columns, values)
+	}
+}
+
+func TestKeyGenerators(t *testing.T) {
+	uuidRe := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([47])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	v4, _ := NewUUIDv4()
+	v7, _ := NewUUIDv7()
+	if m := uuidRe.FindStringSubmatch(v4); m == nil || m[1] != "4" {
+		t.Errorf("invalid UUIDv4: %s", v4)
+	}
+	if m := uuidRe.FindStringSubmatch(v7); m == nil || m[1] != "7" {
+		t.Errorf("invalid UUIDv7: %s", v7)
+	}
+
+	a, _ := NewULID()
+	time.Sleep(2 * time.Millisecond)
+	b, _ := NewULID()
+	if len(a) != 26 || !regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`).MatchString(a) {
+		t.Errorf("invalid ULID: %s", a)
+	}
+	if a >= b {
+		t.Errorf("ULIDs should sort by time: %s >= %s", a, b)
+	}
+
+	prev, _ := NextSnowflakeID()
+	for i := 0; i < 5000; i++ {
+		id, err := NextSnowflakeID()
+		if err != nil || id <= prev {
+			t.Fatalf("snowflake IDs must increase: %d after %d (%v)", id, prev, err)
+		}
+		prev = id
+	}
+	if err := SetSnowflakeNode(2048); err == nil {
+		t.Error("expected error for out-of-range node")
+	}
+
+	RegisterKeyGenerator("fixed", func() (interface{}, error) { return "k-1", nil })
+	m := &struct {
+		Key string `db:"key,pk,generate=fixed"`
+	}{}
+	schema, _ := SchemaOf(m)
+	if err := schema.generateKey(reflect.ValueOf(m).Elem()); err != nil || m.Key != "k-1" {
+		t.Errorf("custom generator not applied: %q (%v)", m.Key, err)
+	}
+}
@@ -0,0 +1,108 @@
+package activerecord
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrStaleObject is matched by errors.Is when an update or delete was
+// rejected because the record changed since it was loaded.
+var ErrStaleObject = errors.New("stale object")
+
+// StaleObjectError reports a write rejected by optimistic locking.
+type StaleObjectError struct {
+	Table   string
+	Key     []interface{}
+	Version int64
+}
+
+func (e *StaleObjectError) Error() string {
+	if e.Key == nil {
+		return fmt.Sprintf("stale object: %s rows were modified by another process", e.Table)
+	}
+	return fmt.Sprintf("stale object: %s %v with lock version %d was modified or deleted by another process",
+		e.Table, e.Key, e.Version)
+}
+
+// Is makes errors.Is(err, ErrStaleObject) match
+func (e *StaleObjectError) Is(target error) bool {
+	return target == ErrStaleObject
+}
+
+// optimisticLock is the lock version state of a model during a write.
+type optimisticLock struct {
+	column  string
+	field   reflect.Value
+	version int64
+}
+
+// lockFor returns the optimistic lock of a model with a lock version
+// column, or nil.
+func lockFor(model interface{}) *optimisticLock {
+	val := reflect.Indirect(reflect.ValueOf(model))
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	f := schemaFor(val.Type()).LockVersion
+	if f == nil {
+		return nil
+	}
+	field, ok := fieldByIndex(val, f.Index, true)
+	if !ok {
+		return nil
+	}
+	version, err := asInt64(field.Interface())
+	if err != nil {
+		return nil
+	}
+	return &optimisticLock{column: f.Column, field: field, version: version}
+}
+
+// set replaces the lock column in the SET list of an UPDATE with the next
+// version.
+func (l *optimisticLock) set(fields []string, values []interface{}) ([]string, []interface{}) {
+	if l == nil {
+		return fields, values
+	}
+	for i, field := range fields {
+		if field == l.column {
+			fields = append(fields[:i:i], fields[i+1:]...)
+			values = append(values[:i:i], values[i+1:]...)
+			break
+		}
+	}
+	return append(fields, l.column), append(values, l.version+1)
+}
+
+// where adds the version check to a WHERE condition.
+func (l *optimisticLock) where(d Dialect, condition string, args []interface{}) (string, []interface{}) {
+	if l == nil {
+		return condition, args
+	}
+	return condition + " AND " + quoteIdentifier(d, l.column) + " = ?", append(args, l.version)
+}
+
+// check returns a StaleObjectError when the write affected no rows.
+func (l *optimisticLock) check(table string, key []interface{}, result sql.Result) error {
+	if l == nil {
+		return nil
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return &StaleObjectError{Table: table, Key: key, Version: l.version}
+	}
+	return nil
+}
+
+// advance sets the model's lock version to the value written by an UPDATE.
+func (l *optimisticLock) advance() {
+	if l == nil {
+		return
+	}
+	_ = assignValue(GetDialect(), l.field, l.version+1)
+}
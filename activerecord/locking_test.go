@@ -0,0 +1,247 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type invoice struct {
+	ActiveRecordModel
+	Number      string `db:"number"`
+	Total       int    `db:"total"`
+	LockVersion int    `db:"lock_version"`
+}
+
+func (i *invoice) TableName() string { return "invoices" }
+
+type hookedInvoice struct {
+	HookableModel
+	Number   string `db:"number"`
+	Total    int    `db:"total"`
+	Revision int64  `db:"revision,lock"`
+}
+
+func (i *hookedInvoice) TableName() string { return "hooked_invoices" }
+
+func setupLockingTestDB(t *testing.T) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	for _, ddl := range []string{
+		`CREATE TABLE invoices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			number TEXT,
+			total INTEGER,
+			lock_version INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP
+		)`,
+		`CREATE TABLE hooked_invoices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			number TEXT,
+			total INTEGER,
+			revision INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP
+		)`,
+	} {
+		if _, err := db.Exec(ddl); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+}
+
+func TestLockVersionSchema(t *testing.T) {
+	if f := schemaFor(reflect.TypeOf(invoice{})).LockVersion; f == nil || f.Column != "lock_version" {
+		t.Errorf("expected lock_version column, got %+v", f)
+	}
+	if f := schemaFor(reflect.TypeOf(hookedInvoice{})).LockVersion; f == nil || f.Column != "revision" {
+		t.Errorf("expected tagged revision column, got %+v", f)
+	}
+	if f := schemaFor(reflect.TypeOf(article{})).LockVersion; f != nil {
+		t.Errorf("expected no lock column, got %+v", f)
+	}
+}
+
+func TestOptimisticLocking(t *testing.T) {
+	setupLockingTestDB(t)
+
+	inv := &invoice{Number: "INV-1", Total: 100}
+	if err := Create(inv); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	a, b := &invoice{}, &invoice{}
+	if err := Find(a, inv.GetID()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if err := Find(b, inv.GetID()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	a.Total = 150
+	if err := a.Update(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if a.LockVersion != 1 {
+		t.Errorf("expected lock version 1, got %d", a.LockVersion)
+	}
+
+	b.Total = 200
+	err := b.Update()
+	if !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("expected ErrStaleObject, got %v", err)
+	}
+	var stale *StaleObjectError
+	if !errors.As(err, &stale) || stale.Table != "invoices" || stale.Version != 0 {
+		t.Errorf("unexpected stale error: %+v", stale)
+	}
+	if b.LockVersion != 0 {
+		t.Errorf("failed update should not advance lock version, got %d", b.LockVersion)
+	}
+
+	if err := Delete(b); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("expected stale delete to fail, got %v", err)
+	}
+
+	// Reloading picks up the current version and the update goes through
+	if err := b.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	b.Total = 200
+	if err := b.Update(); err != nil {
+		t.Fatalf("Update after reload failed: %v", err)
+	}
+	if err := Delete(b); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+}
+
+func TestOptimisticLockingHookableModel(t *testing.T) {
+	setupLockingTestDB(t)
+
+	inv := &hookedInvoice{Number: "INV-2", Total: 10}
+	if err := Create(inv); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	stale := &hookedInvoice{}
+	if err := Find(stale, inv.GetID()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	afterUpdate := 0
+	inv.AddHook(AfterUpdate, func(interface{}) error {
+		afterUpdate++
+		return nil
+	})
+	inv.Total = 20
+	if err := inv.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if inv.Revision != 1 || afterUpdate != 1 {
+		t.Errorf("unexpected state after save: revision=%d hooks=%d", inv.Revision, afterUpdate)
+	}
+
+	stale.AddHook(AfterUpdate, func(interface{}) error {
+		t.Error("after update hook should not run for a stale update")
+		return nil
+	})
+	stale.Total = 30
+	if err := stale.Save(); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("expected ErrStaleObject, got %v", err)
+	}
+	if err := stale.Delete(); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("expected stale delete to fail, got %v", err)
+	}
+}
+
+func TestBulkUpdateLocking(t *testing.T) {
+	setupLockingTestDB(t)
+
+	for _, number := range []string{"INV-3", "INV-4"} {
+		if err := Create(&invoice{Number: number}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	affected, err := BulkUpdateWithOptions(ctx, invoice{},
+		map[string]interface{}{"lock_version": 0},
+		map[string]interface{}{"total": 5},
+		BulkUpdateOptions{LockVersion: true, ExpectedRows: 2})
+	if err != nil || affected != 2 {
+		t.Fatalf("BulkUpdateWithOptions failed: %d, %v", affected, err)
+	}
+
+	var invoices []invoice
+	if err := Where(&invoices, "lock_version = ?", 1); err != nil {
+		t.Fatalf("Where failed: %v", err)
+	}
+	if len(invoices) != 2 {
+		t.Errorf("expected lock versions to be incremented, got %+v", invoices)
+	}
+
+	// The same update again matches no rows at the old version
+	_, err = BulkUpdateWithOptions(ctx, invoice{},
+		map[string]interface{}{"lock_version": 0},
+		map[string]interface{}{"total": 6},
+		BulkUpdateOptions{LockVersion: true, ExpectedRows: 2})
+	if !errors.Is(err, ErrStaleObject) {
+		t.Errorf("expected ErrStaleObject, got %v", err)
+	}
+
+	// Dry runs affect no rows but are not stale
+	c := NewDryRunCollector()
+	_, err = BulkUpdateWithOptions(DryRun(ctx, c), invoice{},
+		map[string]interface{}{"lock_version": 1},
+		map[string]interface{}{"total": 6},
+		BulkUpdateOptions{LockVersion: true, ExpectedRows: 2})
+	if err != nil || len(c.SQL()) != 1 {
+		t.Errorf("unexpected dry-run result: %v, %v", c.SQL(), err)
+	}
+
+	if _, err := BulkUpdateWithOptions(ctx, article{},
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"views": 1},
+		BulkUpdateOptions{LockVersion: true}); err == nil {
+		t.Error("expected an error for a model without lock version column")
+	}
+}
+
+func TestUpdateWithSQLExprLocking(t *testing.T) {
+	setupLockingTestDB(t)
+
+	inv := &invoice{Number: "INV-5", Total: 10}
+	if err := Create(inv); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	stale := &invoice{}
+	if err := Find(stale, inv.GetID()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	if err := UpdateWithSQLExpr(inv, map[string]string{"total": "total + ?"}, 5); err != nil {
+		t.Fatalf("UpdateWithSQLExpr failed: %v", err)
+	}
+	if inv.LockVersion != 1 {
+		t.Errorf("expected lock version 1, got %d", inv.LockVersion)
+	}
+	if err := UpdateWithSQLExpr(stale, map[string]string{"total": "total * 2"}); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("expected ErrStaleObject, got %v", err)
+	}
+	if err := UpdateWithSQLExpr(inv, map[string]string{"lock_version": "0"}); err == nil {
+		t.Error("expected an error for an explicit lock version expression")
+	}
+
+	fresh := &invoice{}
+	if err := Find(fresh, inv.GetID()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if fresh.Total != 15 || fresh.LockVersion != 1 {
+		t.Errorf("unexpected record: total %d, lock version %d", fresh.Total, fresh.LockVersion)
+	}
+}
@@ -300,6 +300,27 @@ type PerformanceMetrics struct {
 	SlowQueries        int64
 	SlowQueryThreshold time.Duration
 	mu                 sync.RWMutex
+
+	capturePlans bool
+	slowLog      []SlowQuery
+	// captures tracks the plan captures still running
+	captures sync.WaitGroup
+}
+
+// maxSlowLog is the number of slow queries kept with their plans
+const maxSlowLog = 100
+
+// planCaptureTimeout bounds the EXPLAIN capturing the plan of a slow query
+const planCaptureTimeout = 5 * time.Second
+
+// SlowQuery is a query over the slow query threshold with its plan
+type SlowQuery struct {
+	Query     string
+	Args      []interface{}
+	Duration  time.Duration
+	Timestamp time.Time
+	Plan      *QueryPlan
+	PlanError error
 }
 
 // NewPerformanceMetrics creates new performance metrics
@@ -322,6 +343,67 @@ func (pm *PerformanceMetrics) RecordQuery(duration time.Duration) {
 	}
 }
 
+// RecordStatement records a query like RecordQuery; see
+// RecordStatementWithContext.
+func (pm *PerformanceMetrics) RecordStatement(query string, args []interface{}, duration time.Duration) {
+	pm.RecordStatementWithContext(context.Background(), query, args, duration)
+}
+
+// RecordStatementWithContext records a query like RecordQuery. When plan
+// capture is enabled, the plan of a query over the slow query threshold is
+// captured with EXPLAIN in the background, so the caller is not delayed and
+// the explain can wait for the connection its rows still hold. The capture
+// keeps the values of ctx but not its cancellation, and is bounded by
+// planCaptureTimeout; its result appears in SlowQueryLog once done, see
+// Flush. Statements recorded in dry-run mode are never explained.
+func (pm *PerformanceMetrics) RecordStatementWithContext(ctx context.Context, query string, args []interface{}, duration time.Duration) {
+	pm.RecordQuery(duration)
+
+	pm.mu.RLock()
+	capture := pm.capturePlans && duration > pm.SlowQueryThreshold
+	pm.mu.RUnlock()
+	if !capture || inDryRun(ctx) {
+		return
+	}
+
+	slow := SlowQuery{Query: query, Args: args, Duration: duration, Timestamp: time.Now()}
+	pm.captures.Add(1)
+	go func() {
+		defer pm.captures.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), planCaptureTimeout)
+		defer cancel()
+		slow.Plan, slow.PlanError = ExplainQuery(ctx, query, args...)
+
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		if len(pm.slowLog) == maxSlowLog {
+			pm.slowLog = pm.slowLog[1:]
+		}
+		pm.slowLog = append(pm.slowLog, slow)
+	}()
+}
+
+// CapturePlans enables or disables capturing the plans of slow queries
+func (pm *PerformanceMetrics) CapturePlans(enabled bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.capturePlans = enabled
+}
+
+// Flush waits for the plans of slow queries still being captured
+func (pm *PerformanceMetrics) Flush() {
+	pm.captures.Wait()
+}
+
+// SlowQueryLog returns the most recent slow queries with their plans,
+// oldest first. Plans still being captured are not included; call Flush
+// first to wait for them.
+func (pm *PerformanceMetrics) SlowQueryLog() []SlowQuery {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return append([]SlowQuery(nil), pm.slowLog...)
+}
+
 // GetStats returns current statistics
 func (pm *PerformanceMetrics) GetStats() map[string]interface{} {
 	pm.mu.RLock()
@@ -349,6 +431,7 @@ func (pm *PerformanceMetrics) Reset() {
 	pm.TotalQueries = 0
 	pm.TotalDuration = 0
 	pm.SlowQueries = 0
+	pm.slowLog = nil
 }
 
 // Global logger instance
@@ -383,7 +466,7 @@ func LoggedExec(query string, args ...interface{}) (sql.Result, error) {
 	result, err := Exec(query, args...)
 
 	GetQueryLogger().LogExec(query, args, start, result, err)
-	GetMetrics().RecordQuery(time.Since(start))
+	GetMetrics().RecordStatement(query, args, time.Since(start))
 
 	return result, err
 }
@@ -394,7 +477,7 @@ func LoggedQuery(query string, args ...interface{}) (*sql.Rows, error) {
 	rows, err := Query(query, args...)
 
 	GetQueryLogger().LogQuery(query, args, start, rows, err)
-	GetMetrics().RecordQuery(time.Since(start))
+	GetMetrics().RecordStatement(query, args, time.Since(start))
 
 	return rows, err
 }
@@ -407,7 +490,7 @@ func LoggedQueryRow(query string, args ...interface{}) *sql.Row {
 	// Note: We can't get the error from sql.Row until Scan is called
 	// So we'll log the query but not the error
 	GetQueryLogger().LogQueryRow(query, args, start, row, nil)
-	GetMetrics().RecordQuery(time.Since(start))
+	GetMetrics().RecordStatement(query, args, time.Since(start))
 
 	return row
 }
@@ -415,10 +498,10 @@ func LoggedQueryRow(query string, args ...interface{}) *sql.Row {
 // LoggedExecWithContext executes a query with context and logging
 func LoggedExecWithContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	start := time.Now()
-	result, err := GetConnection().ExecContext(ctx, query, args...)
+	result, err := ExecWithContext(ctx, query, args...)
 
 	GetQueryLogger().LogExec(query, args, start, result, err)
-	GetMetrics().RecordQuery(time.Since(start))
+	GetMetrics().RecordStatementWithContext(ctx, query, args, time.Since(start))
 
 	return result, err
 }
@@ -426,10 +509,10 @@ func LoggedExecWithContext(ctx context.Context, query string, args ...interface{
 // LoggedQueryWithContext executes a query with context and logging
 func LoggedQueryWithContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	start := time.Now()
-	rows, err := GetConnection().QueryContext(ctx, query, args...)
+	rows, err := QueryWithContext(ctx, query, args...)
 
 	GetQueryLogger().LogQuery(query, args, start, rows, err)
-	GetMetrics().RecordQuery(time.Since(start))
+	GetMetrics().RecordStatementWithContext(ctx, query, args, time.Since(start))
 
 	return rows, err
 }
@@ -437,10 +520,10 @@ func LoggedQueryWithContext(ctx context.Context, query string, args ...interface
 // LoggedQueryRowWithContext executes a query row with context and logging
 func LoggedQueryRowWithContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	start := time.Now()
-	row := GetConnection().QueryRowContext(ctx, query, args...)
+	row := QueryRowWithContext(ctx, query, args...)
 
 	GetQueryLogger().LogQueryRow(query, args, start, row, nil)
-	GetMetrics().RecordQuery(time.Since(start))
+	GetMetrics().RecordStatementWithContext(ctx, query, args, time.Since(start))
 
 	return row
 }
@@ -341,7 +341,7 @@ func (m *MigratorStruct) runMigration(migration MigrationInterface) error {
 	}
 
 	// Add migration record
-	query := "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"
+	query := Rebind(GetDialect(), "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)")
 	_, err = tx.Exec(query, migration.Version(), time.Now())
 	if err != nil {
 		return err
@@ -352,7 +352,7 @@ func (m *MigratorStruct) runMigration(migration MigrationInterface) error {
 }
 
 func (m *MigratorStruct) removeMigrationRecord(version int64) error {
-	query := "DELETE FROM schema_migrations WHERE version = ?"
+	query := Rebind(GetDialect(), "DELETE FROM schema_migrations WHERE version = ?")
 	_, err := m.db.Exec(query, version)
 	return err
 }
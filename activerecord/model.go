@@ -1,6 +1,7 @@
 package activerecord
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -32,7 +33,6 @@ type Modeler interface {
 	GetUpdatedAt() time.Time
 	SetUpdatedAt(time.Time)
 	Find(id interface{}) error
-	Where(query string, args ...interface{}) (interface{}, error)
 }
 
 // BaseModel base model with common fields
@@ -82,12 +82,7 @@ func (m *BaseModel) Find(id interface{}) error {
 	return Find(m, id)
 }
 
-// Where returns a slice of the receiver's type matching the query
-func (m *BaseModel) Where(query string, args ...interface{}) (interface{}, error) {
-	// This would need to be implemented based on the specific model type
-	return []interface{}{nil}, fmt.Errorf("Where method not implemented for generic BaseModel")
-}
-
+// setTimestampsDeep sets CreatedAt and UpdatedAt, including in embedded structs
 func setTimestampsDeep(val reflect.Value, now time.Time) {
 	if val.Kind() == reflect.Ptr {
 		if val.IsNil() {
@@ -100,72 +95,85 @@ func setTimestampsDeep(val reflect.Value, now time.Time) {
 		return
 	}
 
-	typ := val.Type()
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldType := typ.Field(i)
+	schemaFor(val.Type()).setTimestamps(val, now)
+}
 
-		switch {
-		case fieldType.Anonymous && field.Kind() == reflect.Struct:
-			setTimestampsDeep(field, now)
-		case fieldType.Name == "CreatedAt" && field.CanSet():
-			field.Set(reflect.ValueOf(now))
-		case fieldType.Name == "UpdatedAt" && field.CanSet():
-			field.Set(reflect.ValueOf(now))
-		}
+// getFieldsAndValues returns the model's columns and their values
+func getFieldsAndValues(model interface{}, excludeID bool) ([]string, []interface{}) {
+	val := reflect.ValueOf(model)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
 	}
+	return schemaFor(val.Type()).columnValues(val, excludeID)
 }
 
-func getFieldsAndValuesDeep(val reflect.Value, t reflect.Type, excludeID bool,
-	fields *[]string, values *[]interface{}) {
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldType := t.Field(i)
-
-		// Skip unexported fields
-		if !field.CanInterface() {
-			continue
-		}
+// primaryKeyColumns returns the primary key columns of a model, "id" unless
+// declared with `pk` tags, PrimaryKeyer or CompositeKeyer.
+func primaryKeyColumns(model interface{}) []string {
+	if schema, err := SchemaOf(model); err == nil && len(schema.PrimaryKeys) > 0 {
+		return schema.KeyColumns()
+	}
+	return []string{"id"}
+}
 
-		if fieldType.Anonymous && (field.Kind() == reflect.Struct ||
-			(field.Kind() == reflect.Ptr && field.Elem().Kind() == reflect.Struct)) {
-			getFieldsAndValuesDeep(reflect.Indirect(field), reflect.Indirect(field).Type(), excludeID, fields, values)
-			continue
-		}
-		dbTag := fieldType.Tag.Get("db")
-		if dbTag == "" {
-			dbTag = strings.ToLower(fieldType.Name)
-		}
-		if dbTag == "-" {
-			continue
-		}
-		if excludeID && (dbTag == "id" || fieldType.Name == "ID") {
-			continue
-		}
-		// Skip zero time values for created_at and updated_at
-		if (dbTag == "created_at" || dbTag == "updated_at") && field.Type() == reflect.TypeOf(time.Time{}) {
-			if field.Interface().(time.Time).IsZero() {
-				continue
+// primaryKey returns the primary key columns and values of a model.
+func primaryKey(modeler Modeler) ([]string, []interface{}) {
+	val := reflect.Indirect(reflect.ValueOf(modeler))
+	if val.Kind() == reflect.Struct {
+		if schema := schemaFor(val.Type()); len(schema.PrimaryKeys) > 0 {
+			values := make([]interface{}, len(schema.PrimaryKeys))
+			for i, pk := range schema.PrimaryKeys {
+				if field, ok := fieldByIndex(val, pk.Index, false); ok {
+					values[i] = field.Interface()
+				}
 			}
+			return schema.KeyColumns(), values
 		}
-		*fields = append(*fields, dbTag)
-		*values = append(*values, field.Interface())
 	}
+	return []string{"id"}, []interface{}{modeler.GetID()}
 }
 
-func getFieldsAndValues(model interface{}, excludeID bool) ([]string, []interface{}) {
-	val := reflect.ValueOf(model)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+// hasBlankKey reports whether a model's key is a single unset column, which
+// the database is expected to generate.
+func hasBlankKey(modeler Modeler) bool {
+	_, values := primaryKey(modeler)
+	return len(values) == 1 && isBlankKey(values[0])
+}
+
+// whereKey renders the condition matching a row by its key columns.
+func whereKey(d Dialect, columns []string) string {
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = quoteIdentifier(d, column) + " = ?"
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// keyArgs expands an id passed to Find into one argument per key column.
+// Composite keys are passed as a CompositeKey or []interface{}.
+func keyArgs(columns []string, id interface{}) ([]interface{}, error) {
+	var parts []interface{}
+	switch key := id.(type) {
+	case CompositeKey:
+		parts = key
+	case []interface{}:
+		parts = key
+	default:
+		parts = []interface{}{id}
+	}
+	if len(parts) != len(columns) {
+		return nil, fmt.Errorf("primary key has %d columns, got %d values", len(columns), len(parts))
 	}
-	var fields []string
-	var values []interface{}
-	getFieldsAndValuesDeep(val, val.Type(), excludeID, &fields, &values)
-	return fields, values
+	return parts, nil
 }
 
 // Create creates a new record in the database
 func Create(model interface{}) error {
+	return CreateWithContext(context.Background(), model)
+}
+
+// CreateWithContext creates a new record in the database with context
+func CreateWithContext(ctx context.Context, model interface{}) error {
 	modeler, ok := model.(Modeler)
 	if !ok {
 		return ErrNotModeler
@@ -176,116 +184,280 @@ func Create(model interface{}) error {
 	modeler.SetCreatedAt(now)
 	modeler.SetUpdatedAt(now)
 
+	val := reflect.Indirect(reflect.ValueOf(model))
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to a struct")
+	}
+	schema := schemaFor(val.Type())
+	if err := schema.generateKey(val); err != nil {
+		return err
+	}
+
 	// Get fields and values
-	fields, values := getFieldsAndValues(model, false)
+	fields, values := schema.insertValues(val)
 	if len(fields) == 0 {
 		return fmt.Errorf("no fields to insert")
 	}
 
 	// Build query
-	placeholders := make([]string, len(fields))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
+	d := GetDialect()
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		modeler.TableName(),
-		strings.Join(fields, ", "),
-		strings.Join(placeholders, ", "),
+		quoteTable(d, modeler.TableName()),
+		strings.Join(quoteIdentifiers(d, fields), ", "),
+		placeholders(len(fields)),
 	)
 
+	// Read generated values back where LastInsertId is not supported
+	if returning := returningClause(d, schema); returning != "" {
+		rows, err := QueryWithContext(ctx, query+" "+returning, values...)
+		if errors.Is(err, ErrDryRun) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create record: %w", err)
+		}
+		defer rows.Close()
+		if err := scanReturning(rows, schema, val); err != nil {
+			return err
+		}
+		snapshotModel(model)
+		return nil
+	}
+
 	// Execute query
-	result, err := Exec(query, values...)
+	result, err := ExecWithContext(ctx, query, values...)
 	if err != nil {
 		return fmt.Errorf("failed to create record: %w", err)
 	}
+	if inDryRun(ctx) {
+		return nil
+	}
+
+	// Set the generated ID unless the key was supplied or generated client-side
+	if hasBlankKey(modeler) {
+		if id, err := result.LastInsertId(); err == nil {
+			modeler.SetID(id)
+		}
+	}
 
-	// Set the generated ID
-	if id, err := result.LastInsertId(); err == nil {
-		modeler.SetID(id)
+	snapshotModel(model)
+	return nil
+}
+
+// returningClause returns the RETURNING clause for inserts of the schema's
+// model, or "" when the dialect relies on LastInsertId.
+func returningClause(d Dialect, schema *ModelSchema) string {
+	rd, ok := d.(ReturningDialect)
+	if !ok {
+		return ""
 	}
+	columns := schema.returningColumns()
+	if len(columns) == 0 {
+		return ""
+	}
+	return rd.Returning(columns)
+}
+
+// errNoRowReturned is returned when INSERT ... RETURNING produces no row,
+// as for a row skipped by ON CONFLICT DO NOTHING.
+var errNoRowReturned = errors.New("no row returned")
 
+// scanReturning scans the row produced by INSERT ... RETURNING into val.
+func scanReturning(rows *sql.Rows, schema *ModelSchema, val reflect.Value) error {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to create record: %w", err)
+		}
+		return fmt.Errorf("failed to create record: %w", errNoRowReturned)
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+	if err := newRowScanner(schema, columns).scan(rows, val); err != nil {
+		return fmt.Errorf("failed to scan returned columns: %w", err)
+	}
 	return nil
 }
 
 // Find finds a record by ID
 func Find(model interface{}, id interface{}) error {
+	return FindWithContext(context.Background(), model, id)
+}
+
+// FindWithContext finds a record by ID with context
+func FindWithContext(ctx context.Context, model interface{}, id interface{}) error {
 	modeler, ok := model.(Modeler)
 	if !ok {
 		return ErrNotModeler
 	}
 
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = ?", modeler.TableName())
-	rows, err := Query(query, id)
+	columns := primaryKeyColumns(model)
+	args, err := keyArgs(columns, id)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	if !rows.Next() {
-		return ErrNotFound
+	qb := modelQuery(ctx, modeler).Where(whereKey(GetDialect(), columns), args...)
+	if err := qb.First(model); err != nil {
+		return err
 	}
-	return scanRow(rows, model)
+
+	snapshotModel(model)
+	return nil
+}
+
+// modelQuery returns a query builder for a model's table, to which soft
+// deletes and the default scope apply.
+func modelQuery(ctx context.Context, modeler Modeler) *QueryBuilder {
+	qb := NewQueryBuilder(modeler.TableName()).WithContext(ctx)
+	qb.model = reflect.Indirect(reflect.ValueOf(modeler)).Type()
+	return qb
 }
 
 // Update updates a record in the database
 func Update(model interface{}) error {
+	return UpdateWithContext(context.Background(), model)
+}
+
+// UpdateWithContext updates a record in the database with context
+func UpdateWithContext(ctx context.Context, model interface{}) error {
 	modeler, ok := model.(Modeler)
 	if !ok {
 		return ErrNotModeler
 	}
 
-	// Set updated timestamp
-	modeler.SetUpdatedAt(time.Now())
-
-	// Get fields and values
-	fields, values := getFieldsAndValues(model, true)
+	// Get changed fields and values
+	fields, values, changed := updateValues(model, modeler)
+	if !changed {
+		return nil
+	}
 	if len(fields) == 0 {
 		return fmt.Errorf("no fields to update")
 	}
 
+	// Check and increment the lock version, if any
+	lock := lockFor(model)
+	fields, values = lock.set(fields, values)
+
 	// Build query
+	d := GetDialect()
 	setClause := make([]string, len(fields))
 	for i, field := range fields {
-		setClause[i] = fmt.Sprintf("%s = ?", field)
+		setClause[i] = fmt.Sprintf("%s = ?", quoteIdentifier(d, field))
 	}
 
+	keyColumns, keyValues := primaryKey(modeler)
+	where, args := lock.where(d, whereKey(d, keyColumns), keyValues)
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE id = ?",
-		modeler.TableName(),
+		"UPDATE %s SET %s WHERE %s",
+		quoteTable(d, modeler.TableName()),
 		strings.Join(setClause, ", "),
+		where,
 	)
 
 	// Add ID to values
-	values = append(values, modeler.GetID())
+	values = append(values, args...)
 
 	// Execute query
-	_, err := Exec(query, values...)
+	result, err := ExecWithContext(ctx, query, values...)
 	if err != nil {
 		return fmt.Errorf("failed to update record: %w", err)
 	}
+	if inDryRun(ctx) {
+		return nil
+	}
+	if err := lock.check(modeler.TableName(), keyValues, result); err != nil {
+		return err
+	}
+	lock.advance()
 
+	snapshotModel(model)
 	return nil
 }
 
+// updateValues sets updated_at and returns the columns and values an UPDATE
+// writes. Models embedding ActiveRecordModel that were loaded or saved only
+// write their changed columns; changed is false when there is nothing to write.
+func updateValues(model interface{}, modeler Modeler) (fields []string, values []interface{}, changed bool) {
+	changes, tracked := trackedChanges(model)
+	if !tracked {
+		modeler.SetUpdatedAt(time.Now())
+		fields, values = getFieldsAndValues(model, true)
+		return fields, values, true
+	}
+
+	schema := schemaFor(reflect.Indirect(reflect.ValueOf(model)).Type())
+	touched := false
+	for _, c := range changes {
+		switch {
+		case schema.isKey(c.field):
+			continue
+		case c.field == schema.UpdatedAt:
+			touched = true
+			continue
+		}
+		fields = append(fields, c.field.Column)
+		values = append(values, c.new)
+	}
+	if len(fields) == 0 && !touched {
+		return nil, nil, false
+	}
+
+	// Keep an updated_at set explicitly, e.g. by Touch
+	if !touched {
+		modeler.SetUpdatedAt(time.Now())
+	}
+	if schema.UpdatedAt != nil {
+		fields = append(fields, schema.UpdatedAt.Column)
+		values = append(values, modeler.GetUpdatedAt())
+	}
+	return fields, values, true
+}
+
 // Delete deletes a record from the database
 func Delete(model interface{}) error {
+	return DeleteWithContext(context.Background(), model)
+}
+
+// DeleteWithContext deletes a record from the database with context.
+// Soft-deletable records have their deleted_at set instead.
+func DeleteWithContext(ctx context.Context, model interface{}) error {
+	if t := modelStructType(model); t != nil && schemaFor(t).DeletedAt != nil {
+		now := time.Now()
+		return setDeletedAt(ctx, model, &now)
+	}
+	return HardDeleteWithContext(ctx, model)
+}
+
+// Reload reloads a record from the database by its primary key
+func Reload(model interface{}) error {
+	return ReloadWithContext(context.Background(), model)
+}
+
+// ReloadWithContext reloads a record from the database by its primary key with
+// context. Soft-deleted records are reloaded too.
+func ReloadWithContext(ctx context.Context, model interface{}) error {
 	modeler, ok := model.(Modeler)
 	if !ok {
 		return ErrNotModeler
 	}
+	ctx = Unscoped(ctx)
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", modeler.TableName())
-	_, err := Exec(query, modeler.GetID())
-	if err != nil {
-		return fmt.Errorf("failed to delete record: %w", err)
+	_, keyValues := primaryKey(modeler)
+	if len(keyValues) == 1 {
+		return FindWithContext(ctx, model, keyValues[0])
 	}
-
-	return nil
+	return FindWithContext(ctx, model, CompositeKey(keyValues))
 }
 
 // FindAll finds all records
 func FindAll(models interface{}) error {
+	return FindAllWithContext(context.Background(), models)
+}
+
+// FindAllWithContext finds all records with context
+func FindAllWithContext(ctx context.Context, models interface{}) error {
 	val := reflect.ValueOf(models)
 	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
 		return fmt.Errorf("models must be a pointer to a slice")
@@ -303,39 +475,19 @@ func FindAll(models interface{}) error {
 		return ErrNotModeler
 	}
 
-	query := fmt.Sprintf("SELECT * FROM %s", modeler.TableName())
-	rows, err := Query(query)
-	if err != nil {
+	if err := modelQuery(ctx, modeler).Find(models); err != nil {
 		return fmt.Errorf("failed to query records: %w", err)
 	}
-	defer rows.Close()
-
-	// Get column names.
-	_, err = rows.Columns()
-	if err != nil {
-		return fmt.Errorf("failed to get columns: %w", err)
-	}
-
-	// Scan rows.
-	slice := val.Elem()
-	for rows.Next() {
-		// Create a new element.
-		elem := reflect.New(elemType)
-		if err := scanRow(rows, elem.Interface()); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
-		}
-		slice.Set(reflect.Append(slice, elem))
-	}
-
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return nil
 }
 
-// Where fills the receiver slice with records matching the query.
+// Where fills the receiver slice with records matching the query
 func Where(models interface{}, query string, args ...interface{}) error {
+	return WhereWithContext(context.Background(), models, query, args...)
+}
+
+// WhereWithContext fills the receiver slice with records matching the query with context
+func WhereWithContext(ctx context.Context, models interface{}, query string, args ...interface{}) error {
 	// Check if models is a pointer to a slice.
 	val := reflect.ValueOf(models)
 	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
@@ -355,64 +507,14 @@ func Where(models interface{}, query string, args ...interface{}) error {
 		return ErrNotModeler
 	}
 
-	// Build the full query.
-	fullQuery := fmt.Sprintf("SELECT * FROM %s WHERE %s", modeler.TableName(), query)
-
-	rows, err := Query(fullQuery, args...)
-	if err != nil {
+	if err := modelQuery(ctx, modeler).Where(query, args...).Find(models); err != nil {
 		return fmt.Errorf("failed to query records: %w", err)
 	}
-	defer rows.Close()
-
-	// Get column names.
-	_, err = rows.Columns()
-	if err != nil {
-		return fmt.Errorf("failed to get columns: %w", err)
-	}
-
-	// Scan rows.
-	slice := val.Elem()
-	for rows.Next() {
-		// Create a new element.
-		elem := reflect.New(elemType)
-		if err := scanRow(rows, elem.Interface()); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
-		}
-		slice.Set(reflect.Append(slice, elem))
-	}
-
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return nil
 }
 
 // Helper functions
 
-// Helper to recursively build a field map for all exported fields, including embedded structs
-func buildFieldMap(val reflect.Value, typ reflect.Type, prefix string, fieldMap map[string]reflect.Value) {
-	for i := 0; i < typ.NumField(); i++ {
-		fieldType := typ.Field(i)
-		field := val.Field(i)
-		if fieldType.Anonymous && field.Kind() == reflect.Struct {
-			buildFieldMap(field, field.Type(), prefix, fieldMap)
-			continue
-		}
-		if !field.CanSet() {
-			continue
-		}
-		dbTag := fieldType.Tag.Get("db")
-		if dbTag == "" {
-			dbTag = strings.ToLower(fieldType.Name)
-		}
-		if dbTag == "-" {
-			continue
-		}
-		fieldMap[dbTag] = field
-	}
-}
-
 // scanRow scans a database row into a model.
 func scanRow(row interface{}, model interface{}) error {
 	val := reflect.ValueOf(model)
@@ -422,92 +524,66 @@ func scanRow(row interface{}, model interface{}) error {
 	if val.Kind() != reflect.Struct {
 		return fmt.Errorf("scanRow: model is not a struct")
 	}
-	typ := val.Type()
 
 	columns, err := getColumns(row)
 	if err != nil {
 		return err
 	}
 
-	scanArgs := make([]interface{}, len(columns))
-	fieldMap := make(map[string]reflect.Value)
-	buildFieldMap(val, typ, "", fieldMap)
+	return newRowScanner(schemaFor(val.Type()), columns).scan(row, val)
+}
+
+// rowScanner maps result columns to schema fields once per result set
+// so that scanning each row needs no further field lookups.
+type rowScanner struct {
+	dialect Dialect
+	fields  []*FieldSchema
+	raw     []interface{}
+	args    []interface{}
+}
 
+func newRowScanner(schema *ModelSchema, columns []string) *rowScanner {
+	rs := &rowScanner{
+		dialect: GetDialect(),
+		fields:  make([]*FieldSchema, len(columns)),
+		raw:     make([]interface{}, len(columns)),
+		args:    make([]interface{}, len(columns)),
+	}
 	for i, col := range columns {
-		if field, ok := fieldMap[col]; ok {
-			// Handle NULL values based on field type
-			switch field.Kind() {
-			case reflect.String:
-				var nullStr sql.NullString
-				scanArgs[i] = &nullStr
-			case reflect.Int, reflect.Int64:
-				var nullInt sql.NullInt64
-				scanArgs[i] = &nullInt
-			case reflect.Int32:
-				var nullInt32 sql.NullInt32
-				scanArgs[i] = &nullInt32
-			case reflect.Int16:
-				var nullInt16 sql.NullInt16
-				scanArgs[i] = &nullInt16
-			case reflect.Float64:
-				var nullFloat sql.NullFloat64
-				scanArgs[i] = &nullFloat
-			case reflect.Bool:
-				var nullBool sql.NullBool
-				scanArgs[i] = &nullBool
-			default:
-				scanArgs[i] = field.Addr().Interface()
-			}
-		} else {
-			var dummy interface{}
-			scanArgs[i] = &dummy
+		if field, ok := schema.FieldByColumn(col); ok {
+			rs.fields[i] = field
 		}
+		rs.args[i] = &rs.raw[i]
 	}
+	return rs
+}
 
+// scan scans the current row into the struct value val.
+func (rs *rowScanner) scan(row interface{}, val reflect.Value) error {
 	switch r := row.(type) {
 	case *sql.Row:
-		if err := r.Scan(scanArgs...); err != nil {
+		if err := r.Scan(rs.args...); err != nil {
 			return err
 		}
 	case *sql.Rows:
-		if err := r.Scan(scanArgs...); err != nil {
+		if err := r.Scan(rs.args...); err != nil {
 			return err
 		}
 	default:
 		return fmt.Errorf("unsupported row type: %T", row)
 	}
 
-	// Set the field values from the scanned data
-	for i, col := range columns {
-		if field, ok := fieldMap[col]; ok {
-			switch field.Kind() {
-			case reflect.String:
-				if nullStr, ok := scanArgs[i].(*sql.NullString); ok && nullStr.Valid {
-					field.SetString(nullStr.String)
-				}
-			case reflect.Int, reflect.Int64:
-				if nullInt, ok := scanArgs[i].(*sql.NullInt64); ok && nullInt.Valid {
-					field.SetInt(nullInt.Int64)
-				}
-			case reflect.Int32:
-				if nullInt32, ok := scanArgs[i].(*sql.NullInt32); ok && nullInt32.Valid {
-					field.SetInt(int64(nullInt32.Int32))
-				}
-			case reflect.Int16:
-				if nullInt16, ok := scanArgs[i].(*sql.NullInt16); ok && nullInt16.Valid {
-					field.SetInt(int64(nullInt16.Int16))
-				}
-			case reflect.Float64:
-				if nullFloat, ok := scanArgs[i].(*sql.NullFloat64); ok && nullFloat.Valid {
-					field.SetFloat(nullFloat.Float64)
-				}
-			case reflect.Bool:
-				if nullBool, ok := scanArgs[i].(*sql.NullBool); ok && nullBool.Valid {
-					field.SetBool(nullBool.Bool)
-				}
-			default:
-				// For other types, the value was already set directly
-			}
+	// Convert the raw values into the model's fields
+	for i, f := range rs.fields {
+		if f == nil {
+			continue
+		}
+		field, ok := fieldByIndex(val, f.Index, true)
+		if !ok || !field.CanSet() {
+			continue
+		}
+		if err := assignValue(rs.dialect, field, rs.raw[i]); err != nil {
+			return fmt.Errorf("failed to scan column %s: %w", f.Column, err)
 		}
 	}
 
@@ -0,0 +1,347 @@
+package activerecord
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded or do not
+// match the keyset.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// keysetColumn is a column of the ordered unique column set used for keyset
+// pagination.
+type keysetColumn struct {
+	column string
+	desc   bool
+}
+
+// Keyset sets the ordered unique columns used by After, Before, Cursor and
+// FindInBatches, e.g. Keyset("created_at DESC", "id DESC"). It defaults to
+// the model's primary key, ascending. Keyset ordering precedes OrderBy.
+func (qb *QueryBuilder) Keyset(columns ...string) *QueryBuilder {
+	qb.keyset = make([]keysetColumn, 0, len(columns))
+	for _, column := range columns {
+		fields := strings.Fields(column)
+		if len(fields) == 0 {
+			return qb.fail(fmt.Errorf("%w: %q", ErrInvalidIdentifier, column))
+		}
+		desc, nulls, err := parseDirection(strings.Join(fields[1:], " "))
+		if err == nil && nulls != "" {
+			err = fmt.Errorf("%w: keyset columns cannot sort NULLS %s", ErrInvalidDirection, nulls)
+		}
+		if err == nil {
+			err = checkIdentifier(fields[0])
+		}
+		if err != nil {
+			return qb.fail(err)
+		}
+		qb.keyset = append(qb.keyset, keysetColumn{column: fields[0], desc: desc})
+	}
+	return qb
+}
+
+// After limits the results to rows following the cursor in keyset order
+func (qb *QueryBuilder) After(cursor string) *QueryBuilder {
+	return qb.setCursor(cursor, false)
+}
+
+// Before limits the results to rows preceding the cursor in keyset order.
+// Find still returns them in keyset order.
+func (qb *QueryBuilder) Before(cursor string) *QueryBuilder {
+	return qb.setCursor(cursor, true)
+}
+
+func (qb *QueryBuilder) setCursor(cursor string, before bool) *QueryBuilder {
+	values, err := decodeCursor(cursor)
+	if err != nil {
+		return qb.fail(err)
+	}
+	qb.cursor = values
+	qb.cursorBefore = before
+	return qb
+}
+
+// Cursor returns the opaque cursor of a model, or of a map of column values,
+// for use with After and Before
+func (qb *QueryBuilder) Cursor(model interface{}) (string, error) {
+	if qb.model == nil {
+		qb.model = modelStructType(model)
+	}
+	values, err := qb.keyValues(model)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values []interface{}
+	if err := decoder.Decode(&values); err != nil || len(values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	for i, v := range values {
+		if n, ok := v.(json.Number); ok {
+			if values[i], err = n.Int64(); err != nil {
+				values[i], _ = n.Float64()
+			}
+		}
+	}
+	return values, nil
+}
+
+// keysetColumns returns the keyset, defaulting to the model's primary key.
+func (qb *QueryBuilder) keysetColumns() []keysetColumn {
+	if len(qb.keyset) > 0 {
+		return qb.keyset
+	}
+	columns := qb.keyColumns()
+	keyset := make([]keysetColumn, len(columns))
+	for i, column := range columns {
+		keyset[i] = keysetColumn{column: column}
+	}
+	return keyset
+}
+
+// keysetOrder returns the ORDER BY terms of the keyset, reversed for Before.
+func (qb *QueryBuilder) keysetOrder() []Expr {
+	if len(qb.keyset) == 0 && qb.cursor == nil {
+		return nil
+	}
+	keyset := qb.keysetColumns()
+	order := make([]Expr, len(keyset))
+	for i, k := range keyset {
+		order[i] = orderExpr{column: k.column, desc: k.desc != qb.cursorBefore}
+	}
+	return order
+}
+
+// cursorCondition returns the condition selecting rows past the cursor:
+// (a > ?) OR (a = ? AND b > ?) and so on, which works for mixed directions.
+func (qb *QueryBuilder) cursorCondition(d Dialect) Expr {
+	if qb.cursor == nil {
+		return nil
+	}
+	keyset := qb.keysetColumns()
+	values := qb.cursorValues(d, keyset)
+
+	alternatives := make([]Expr, len(keyset))
+	for i, k := range keyset {
+		terms := make([]Expr, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, Eq(keyset[j].column, values[j]))
+		}
+		op := ">"
+		if k.desc != qb.cursorBefore {
+			op = "<"
+		}
+		terms = append(terms, compareExpr{column: k.column, op: op, value: values[i]})
+		alternatives[i] = And(terms...)
+	}
+	return Or(alternatives...)
+}
+
+// cursorValues converts the cursor's values to the types of the model's
+// keyset fields, when known.
+func (qb *QueryBuilder) cursorValues(d Dialect, keyset []keysetColumn) []interface{} {
+	values := append([]interface{}{}, qb.cursor...)
+	if qb.model == nil {
+		return values
+	}
+	schema := schemaFor(qb.model)
+	for i, k := range keyset {
+		f, ok := schema.FieldByColumn(unqualified(k.column))
+		if !ok {
+			continue
+		}
+		typed := reflect.New(f.Type).Elem()
+		if err := assignValue(d, typed, values[i]); err == nil {
+			values[i] = typed.Interface()
+		}
+	}
+	return values
+}
+
+// checkCursor reports an invalid After or Before cursor.
+func (qb *QueryBuilder) checkCursor() error {
+	if qb.cursor != nil && len(qb.cursor) != len(qb.keysetColumns()) {
+		return fmt.Errorf("%w: expected %d values, got %d", ErrInvalidCursor, len(qb.keysetColumns()), len(qb.cursor))
+	}
+	return nil
+}
+
+// keyValues returns the keyset values of a model or a map of column values.
+func (qb *QueryBuilder) keyValues(item interface{}) ([]interface{}, error) {
+	keyset := qb.keysetColumns()
+	values := make([]interface{}, len(keyset))
+
+	if m, ok := item.(map[string]interface{}); ok {
+		for i, k := range keyset {
+			v, ok := m[unqualified(k.column)]
+			if !ok {
+				return nil, fmt.Errorf("missing keyset column %s", k.column)
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+
+	val := reflect.Indirect(reflect.ValueOf(item))
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot read keyset values from %T", item)
+	}
+	schema := schemaFor(val.Type())
+	for i, k := range keyset {
+		f, ok := schema.FieldByColumn(unqualified(k.column))
+		if !ok {
+			return nil, fmt.Errorf("missing keyset column %s", k.column)
+		}
+		fv, ok := fieldByIndex(val, f.Index, false)
+		if !ok {
+			return nil, fmt.Errorf("missing keyset column %s", k.column)
+		}
+		values[i] = fv.Interface()
+	}
+	return values, nil
+}
+
+// unqualified strips the table from a column name.
+func unqualified(column string) string {
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		return column[i+1:]
+	}
+	return column
+}
+
+// Pagination describes a page returned by Paginate
+type Pagination struct {
+	Page       int
+	PerPage    int
+	Total      int64
+	TotalPages int
+}
+
+// HasNext reports whether there is a page after this one
+func (p *Pagination) HasNext() bool { return p.Page < p.TotalPages }
+
+// HasPrev reports whether there is a page before this one
+func (p *Pagination) HasPrev() bool { return p.Page > 1 }
+
+// Paginate fills models with the given 1-based page and returns the total
+// row and page counts
+func (qb *QueryBuilder) Paginate(page, perPage int, models interface{}) (*Pagination, error) {
+	if perPage <= 0 {
+		return nil, fmt.Errorf("per page must be positive, got %d", perPage)
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	total, err := qb.Count()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	if v := reflect.ValueOf(models); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+		v.Elem().SetLen(0) // Find appends
+	}
+	q := qb.clone()
+	q.Limit(perPage).Offset((page - 1) * perPage)
+	if err := q.Find(models); err != nil {
+		return nil, err
+	}
+
+	return &Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int((total + int64(perPage) - 1) / int64(perPage)),
+	}, nil
+}
+
+// findBatch fetches one batch as models, or as maps of column values when
+// the model type is unknown.
+func (qb *QueryBuilder) findBatch() ([]interface{}, error) {
+	if qb.model == nil {
+		rows, err := qb.Execute()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return scanMaps(rows)
+	}
+
+	batch := reflect.New(reflect.SliceOf(reflect.PtrTo(qb.model)))
+	if err := qb.Find(batch.Interface()); err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, batch.Elem().Len())
+	for i := range items {
+		items[i] = batch.Elem().Index(i).Interface()
+	}
+	return items, nil
+}
+
+// scanMaps scans rows into maps of column values.
+func scanMaps(rows *sql.Rows) ([]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	var items []interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		item := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			item[column] = values[i]
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// sliceLen returns the length of the slice models points to, or 0 if it
+// does not point to a slice.
+func sliceLen(models interface{}) int {
+	v := reflect.ValueOf(models)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return 0
+	}
+	return v.Elem().Len()
+}
+
+// reverseSlice reverses the elements of the slice models points to from
+// index from on.
+func reverseSlice(models interface{}, from int) {
+	slice := reflect.ValueOf(models).Elem()
+	swap := reflect.Swapper(slice.Interface())
+	for i, j := from, slice.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
@@ -0,0 +1,159 @@
+package activerecord
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func articleTitles(articles []*article) []string {
+	titles := make([]string, len(articles))
+	for i, a := range articles {
+		titles[i] = a.Title
+	}
+	return titles
+}
+
+func TestPaginate(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"a", "b", "c", "d", "e"} {
+		if err := Create(&article{Title: title, Views: i}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var page []*article
+	p, err := NewQueryBuilder("articles").OrderBy("title", "asc").Paginate(2, 2, &page)
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if p.Total != 5 || p.TotalPages != 3 || !p.HasNext() || !p.HasPrev() {
+		t.Errorf("unexpected pagination: %+v", p)
+	}
+	if !reflect.DeepEqual(articleTitles(page), []string{"c", "d"}) {
+		t.Errorf("unexpected page: %v", articleTitles(page))
+	}
+
+	p, err = NewQueryBuilder("articles").OrderBy("title", "asc").Paginate(3, 2, &page)
+	if err != nil || p.HasNext() || len(page) != 1 {
+		t.Errorf("unexpected last page: %+v, %d, %v", p, len(page), err)
+	}
+}
+
+func TestKeysetPagination(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"a", "b", "c", "d", "e"} {
+		if err := Create(&article{Title: title, Views: (i % 2) * 10}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var first []*article
+	if err := NewQueryBuilder("articles").Keyset("id").Limit(2).Find(&first); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(first), []string{"a", "b"}) {
+		t.Fatalf("unexpected first page: %v", articleTitles(first))
+	}
+
+	cursor := mustCursor(t, first[1])
+	var next []*article
+	if err := NewQueryBuilder("articles").Limit(2).After(cursor).Find(&next); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(next), []string{"c", "d"}) {
+		t.Errorf("unexpected next page: %v", articleTitles(next))
+	}
+
+	var prev []*article
+	if err := NewQueryBuilder("articles").Limit(2).Before(mustCursor(t, next[1])).Find(&prev); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(prev), []string{"b", "c"}) {
+		t.Errorf("unexpected previous page: %v", articleTitles(prev))
+	}
+
+	// Only the appended page is reversed
+	pages := append([]*article{}, next...)
+	if err := NewQueryBuilder("articles").Limit(2).Before(mustCursor(t, next[1])).Find(&pages); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(pages), []string{"c", "d", "b", "c"}) {
+		t.Errorf("unexpected appended page: %v", articleTitles(pages))
+	}
+
+	// Mixed directions: views descending, then id descending
+	qb := NewQueryBuilder("articles").Keyset("views DESC", "id DESC")
+	var byViews []*article
+	if err := qb.Limit(2).Find(&byViews); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(byViews), []string{"d", "b"}) {
+		t.Fatalf("unexpected keyset page: %v", articleTitles(byViews))
+	}
+	cursor, err := qb.Cursor(byViews[1])
+	if err != nil {
+		t.Fatalf("Cursor failed: %v", err)
+	}
+	var rest []*article
+	if err := NewQueryBuilder("articles").Keyset("views DESC", "id DESC").After(cursor).Find(&rest); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(rest), []string{"e", "c", "a"}) {
+		t.Errorf("unexpected keyset rest: %v", articleTitles(rest))
+	}
+
+	if err := NewQueryBuilder("articles").After("not a cursor").Find(&rest); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	if err := NewQueryBuilder("articles").Keyset("views", "id").After(mustCursor(t, first[0])).Find(&rest); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for a short cursor, got %v", err)
+	}
+}
+
+func mustCursor(t *testing.T, a *article) string {
+	t.Helper()
+	cursor, err := NewQueryBuilder("articles").Cursor(a)
+	if err != nil {
+		t.Fatalf("Cursor failed: %v", err)
+	}
+	return cursor
+}
+
+func TestFindInBatchesKeyset(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i := 0; i < 5; i++ {
+		if err := Create(&article{Title: "t", Views: i}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	// Deleting rows between batches must not skip any with keyset paging
+	var seen []int
+	err := NewQueryBuilder("articles").FindInBatches(2, func(batch []interface{}) error {
+		for _, item := range batch {
+			seen = append(seen, int(item.(map[string]interface{})["views"].(int64)))
+		}
+		_, err := GetConnection().Exec("DELETE FROM articles WHERE id = ?", batch[0].(map[string]interface{})["id"])
+		return err
+	})
+	if err != nil {
+		t.Fatalf("FindInBatches failed: %v", err)
+	}
+	if !reflect.DeepEqual(seen, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("unexpected batches: %v", seen)
+	}
+
+	var count int
+	err = FindInBatches(&article{}, 2, func(batch []interface{}) error {
+		for _, item := range batch {
+			if _, ok := item.(*article); !ok {
+				t.Errorf("unexpected item type %T", item)
+			}
+		}
+		count += len(batch)
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Errorf("unexpected typed batches: %d, %v", count, err)
+	}
+}
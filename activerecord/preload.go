@@ -0,0 +1,594 @@
+package activerecord
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// preloadBatchSize is the most parent keys sent in one preload query.
+const preloadBatchSize = 1000
+
+// preloadOwnerKey aliases the join table's owner key column selected by
+// many-to-many preloads.
+const preloadOwnerKey = "ar_preload_owner"
+
+// preloadRank and preloadRanked name the row number and the ranked rows of
+// preloads limited per parent.
+const (
+	preloadRank   = "ar_preload_rank"
+	preloadRanked = "ar_preload_ranked"
+)
+
+// PreloadWithContext loads the named associations of models, a pointer to
+// a model or to a slice of models, with one IN (...) query per association
+// for all of them, and sets the association fields. Parents without related
+// records get a nil pointer or an empty slice. A dotted path such as
+// "Posts.Comments.Author" also loads the associations of the loaded records.
+//
+//	var users []*User
+//	err := FindAll(&users)
+//	err = PreloadWithContext(ctx, &users, "Posts.Comments", "Profile")
+func PreloadWithContext(ctx context.Context, models interface{}, associations ...string) error {
+	specs := make([]preloadSpec, len(associations))
+	for i, path := range associations {
+		specs[i] = preloadSpec{path: path}
+	}
+	return preload(ctx, models, specs)
+}
+
+// preloadSpec is an association path to preload, with an optional function
+// customizing the query of its last association.
+type preloadSpec struct {
+	path string
+	fn   func(*QueryBuilder)
+}
+
+// preloadNode is an association to preload and the associations to preload
+// on its records in turn.
+type preloadNode struct {
+	name     string
+	fns      []func(*QueryBuilder)
+	children []*preloadNode
+}
+
+// preloadTree merges association paths into a tree, keeping their order.
+func preloadTree(specs []preloadSpec) []*preloadNode {
+	var roots []*preloadNode
+	for _, spec := range specs {
+		nodes := &roots
+		var node *preloadNode
+		for _, name := range strings.Split(spec.path, ".") {
+			node = nil
+			for _, n := range *nodes {
+				if n.name == name {
+					node = n
+					break
+				}
+			}
+			if node == nil {
+				node = &preloadNode{name: name}
+				*nodes = append(*nodes, node)
+			}
+			nodes = &node.children
+		}
+		if spec.fn != nil {
+			node.fns = append(node.fns, spec.fn)
+		}
+	}
+	return roots
+}
+
+// preload loads association paths into models.
+func preload(ctx context.Context, models interface{}, specs []preloadSpec) error {
+	parents, err := preloadParents(models)
+	if err != nil {
+		return err
+	}
+	return preloadNodes(ctx, parents, preloadTree(specs))
+}
+
+// preloadNodes loads associations into parents of one type.
+func preloadNodes(ctx context.Context, parents []reflect.Value, nodes []*preloadNode) error {
+	if len(parents) == 0 {
+		return nil
+	}
+	for _, node := range nodes {
+		if err := preloadAssociation(ctx, parents, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preloadParents returns the addressable struct values of a pointer to a
+// model or to a slice of models, skipping nil elements.
+func preloadParents(models interface{}) ([]reflect.Value, error) {
+	val := reflect.ValueOf(models)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil, fmt.Errorf("models must be a pointer to a model or to a slice of models")
+	}
+	val = val.Elem()
+	if val.Kind() == reflect.Struct {
+		return []reflect.Value{val}, nil
+	}
+	if val.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("models must be a pointer to a model or to a slice of models")
+	}
+
+	parents := make([]reflect.Value, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		elem := val.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("models must be a slice of structs")
+		}
+		parents = append(parents, elem)
+	}
+	return parents, nil
+}
+
+// preloadPlan describes how to load one association for a set of parents.
+type preloadPlan struct {
+	name   string
+	assoc  *Association
+	rel    *RelationField
+	target *ModelSchema
+	table  string
+	// ownerFields hold the parent's side of the key
+	ownerFields []*FieldSchema
+	// keyColumns are the columns of the target, or of the through table,
+	// matched against the parent keys
+	keyColumns []string
+	// resultColumns are the columns of the result holding the parent key
+	resultColumns []string
+	// join is the join table of many-to-many associations
+	join *joinTable
+	// fns customize the query, as added by PreloadWith
+	fns []func(*QueryBuilder)
+}
+
+// preloadAssociation loads one association into the parents, then its
+// nested associations into the loaded records.
+func preloadAssociation(ctx context.Context, parents []reflect.Value, node *preloadNode) error {
+	name := node.name
+	plan, err := newPreloadPlan(parents[0].Type(), name)
+	if err != nil {
+		return err
+	}
+	plan.fns = node.fns
+
+	// Collect the distinct parent keys
+	var keys [][]interface{}
+	seen := make(map[string]bool)
+	for _, parent := range parents {
+		key, ok := plan.ownerKey(parent)
+		if !ok {
+			continue
+		}
+		s := keyString(key)
+		if !seen[s] {
+			seen[s] = true
+			keys = append(keys, key)
+		}
+	}
+
+	related := make(map[string][]reflect.Value)
+	for start := 0; start < len(keys); start += preloadBatchSize {
+		end := start + preloadBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := plan.load(ctx, keys[start:end], related); err != nil {
+			return fmt.Errorf("failed to preload %s: %w", name, err)
+		}
+	}
+
+	for _, parent := range parents {
+		var items []reflect.Value
+		if key, ok := plan.ownerKey(parent); ok {
+			items = related[keyString(key)]
+		}
+		if err := plan.assign(parent, items); err != nil {
+			return err
+		}
+	}
+
+	if len(node.children) == 0 {
+		return nil
+	}
+	if err := preloadNodes(ctx, plan.loaded(parents), node.children); err != nil {
+		return fmt.Errorf("failed to preload %s: %w", name, err)
+	}
+	return nil
+}
+
+// newPreloadPlan resolves an association of a parent type.
+func newPreloadPlan(parentType reflect.Type, name string) (*preloadPlan, error) {
+	parent := schemaFor(parentType)
+	rel, ok := parent.Relation(name)
+	if !ok {
+		return nil, fmt.Errorf("%s has no association field %s", parentType.Name(), name)
+	}
+	assoc, err := associationFor(parentType, name)
+	if err != nil {
+		return nil, err
+	}
+	if assoc.many() != rel.Many {
+		return nil, fmt.Errorf("association %s does not match the type of field %s.%s", name, parentType.Name(), name)
+	}
+
+	plan, err := newAssociationPlan(name, assoc, parentType, rel.Elem)
+	if err != nil {
+		return nil, err
+	}
+	plan.rel = rel
+	return plan, nil
+}
+
+// resolveOwner sets the parent fields holding the owner's side of the key.
+func (p *preloadPlan) resolveOwner(parent *ModelSchema) error {
+	switch p.assoc.Type {
+	case BelongsTo:
+		names := p.assoc.ForeignKeys
+		if len(names) == 0 {
+			names = []string{p.assoc.ForeignKey}
+		}
+		for _, n := range names {
+			f, ok := fieldOf(parent, n)
+			if !ok {
+				return fmt.Errorf("association %s: %s has no field %s", p.name, parent.Type.Name(), n)
+			}
+			p.ownerFields = append(p.ownerFields, f)
+		}
+		if len(p.keyColumns) != len(p.ownerFields) {
+			return fmt.Errorf("association %s: %d foreign keys reference a primary key of %d columns",
+				p.name, len(p.ownerFields), len(p.keyColumns))
+		}
+	case HasOne, HasMany:
+		owner, err := ownerKeyField(parent, p.assoc.LocalKey)
+		if err != nil {
+			return fmt.Errorf("association %s: %w", p.name, err)
+		}
+		p.ownerFields = []*FieldSchema{owner}
+	case HasManyThrough, HasAndBelongsToMany:
+		owner, err := ownerKeyField(parent, "")
+		if err != nil {
+			return fmt.Errorf("association %s: %w", p.name, err)
+		}
+		p.ownerFields = []*FieldSchema{owner}
+	}
+	return nil
+}
+
+// newAssociationPlan resolves an association of owner to records of type
+// elem: the owner fields and the columns matched against their values.
+func newAssociationPlan(name string, assoc *Association, owner, elem reflect.Type) (*preloadPlan, error) {
+	modeler, ok := reflect.New(elem).Interface().(Modeler)
+	if !ok {
+		return nil, fmt.Errorf("association %s: %s does not implement Modeler", name, elem.Name())
+	}
+	plan := &preloadPlan{
+		name:   name,
+		assoc:  assoc,
+		target: schemaFor(elem),
+		table:  modeler.TableName(),
+	}
+
+	switch assoc.Type {
+	case BelongsTo:
+		plan.keyColumns = plan.target.KeyColumns()
+		plan.resultColumns = plan.keyColumns
+	case HasOne, HasMany:
+		plan.keyColumns = []string{columnOf(plan.target, assoc.ForeignKey)}
+		plan.resultColumns = plan.keyColumns
+	case HasManyThrough, HasAndBelongsToMany:
+		if len(plan.target.PrimaryKeys) != 1 {
+			return nil, fmt.Errorf("association %s: %s must have a single-column primary key", name, elem.Name())
+		}
+		join, err := joinTableOf(name, assoc, owner, elem)
+		if err != nil {
+			return nil, err
+		}
+		plan.join = join
+		plan.keyColumns = []string{join.table + "." + join.ownerColumn}
+		plan.resultColumns = []string{preloadOwnerKey}
+	default:
+		return nil, fmt.Errorf("unsupported association type")
+	}
+	if err := plan.resolveOwner(schemaFor(owner)); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ownerKeyField returns the parent field referenced by the foreign key of
+// has-one and has-many associations: the named field, or the primary key.
+func ownerKeyField(s *ModelSchema, name string) (*FieldSchema, error) {
+	if name != "" {
+		f, ok := fieldOf(s, name)
+		if !ok {
+			return nil, fmt.Errorf("%s has no field %s", s.Type.Name(), name)
+		}
+		return f, nil
+	}
+	if len(s.PrimaryKeys) != 1 {
+		return nil, fmt.Errorf("%s must have a single-column primary key", s.Type.Name())
+	}
+	return s.PrimaryKeys[0], nil
+}
+
+// fieldOf returns the field with the given Go name or column.
+func fieldOf(s *ModelSchema, name string) (*FieldSchema, bool) {
+	if f, ok := s.FieldByName(name); ok {
+		return f, true
+	}
+	return s.FieldByColumn(name)
+}
+
+// columnOf returns the column of the field with the given Go name or
+// column, or name itself when the model has no such field.
+func columnOf(s *ModelSchema, name string) string {
+	if f, ok := fieldOf(s, name); ok {
+		return f.Column
+	}
+	return name
+}
+
+// ownerKey returns the parent's key values, or false when the key is unset.
+func (p *preloadPlan) ownerKey(parent reflect.Value) ([]interface{}, bool) {
+	key := make([]interface{}, len(p.ownerFields))
+	for i, f := range p.ownerFields {
+		field, ok := fieldByIndex(parent, f.Index, false)
+		if !ok || isBlankKey(field.Interface()) {
+			return nil, false
+		}
+		key[i] = field.Interface()
+	}
+	return key, true
+}
+
+// query builds the query loading the related records of the parent keys.
+func (p *preloadPlan) query(ctx context.Context, keys [][]interface{}) (*QueryBuilder, error) {
+	qb := NewQueryBuilder(p.table).WithContext(ctx)
+	qb.model = p.target.Type
+	qb.SelectExpr(columnExpr{name: p.table + ".*"})
+
+	if p.join != nil {
+		through, fk, lk := p.join.table, p.join.ownerColumn, p.join.targetColumn
+		qb.selectFields = append(qb.selectFields, columnExpr{name: through + "." + fk, alias: preloadOwnerKey})
+		qb.Join(through, fmt.Sprintf("%s.%s = %s.%s", through, lk, p.table, p.target.PrimaryKey.Column))
+	}
+	for _, fn := range p.fns {
+		fn(qb)
+	}
+	if err := qb.validate(); err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(p.keyColumns))
+	for i, column := range p.keyColumns {
+		if !strings.Contains(column, ".") {
+			column = p.table + "." + column
+		}
+		columns[i] = column
+	}
+	if len(columns) == 1 {
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = key[0]
+		}
+		qb.WhereExpr(In(columns[0], values...))
+	} else {
+		alternatives := make([]Expr, len(keys))
+		for i, key := range keys {
+			parts := make([]Expr, len(columns))
+			for j, column := range columns {
+				parts[j] = Eq(column, key[j])
+			}
+			alternatives[i] = And(parts...)
+		}
+		qb.WhereExpr(Or(alternatives...))
+	}
+
+	if p.assoc.Order != "" {
+		column, direction, _ := strings.Cut(strings.TrimSpace(p.assoc.Order), " ")
+		if !strings.Contains(column, ".") {
+			column = p.table + "." + column
+		}
+		qb.OrderBy(column, direction)
+		if err := qb.validate(); err != nil {
+			return nil, fmt.Errorf("association %s: %w", p.name, err)
+		}
+	}
+	for _, column := range p.target.KeyColumns() {
+		qb.OrderBy(p.table+"."+column, "ASC")
+	}
+
+	if qb.limit > 0 || qb.offset > 0 {
+		return p.limitPerParent(qb, columns), nil
+	}
+	return qb, nil
+}
+
+// limitPerParent applies the limit and offset of an association query to
+// the records of each parent rather than to all of them, numbering the
+// records of each parent in the query's order.
+func (p *preloadPlan) limitPerParent(qb *QueryBuilder, partition []string) *QueryBuilder {
+	limit, offset := qb.limit, qb.offset
+	qb.limit, qb.offset = 0, 0
+	qb.selectFields = append(qb.selectFields, rankExpr{partition: partition, order: qb.orderBy, alias: preloadRank})
+	qb.orderBy = nil
+
+	ranked := NewQueryBuilder(preloadRanked).WithContext(qb.ctx).With(preloadRanked, qb)
+	ranked.unscoped = true
+	ranked.WhereExpr(Gt(preloadRank, offset))
+	if limit > 0 {
+		ranked.WhereExpr(Lte(preloadRank, offset+limit))
+	}
+	return ranked.OrderBy(preloadRank, "ASC")
+}
+
+// rankExpr numbers the rows of each partition in order, from 1.
+type rankExpr struct {
+	partition []string
+	order     []Expr
+	alias     string
+}
+
+func (e rankExpr) SQL(d Dialect) (string, []interface{}) {
+	sql := "ROW_NUMBER() OVER (PARTITION BY " + strings.Join(quoteIdentifiers(d, e.partition), ", ")
+	var args []interface{}
+	if len(e.order) > 0 {
+		var orderSQL string
+		orderSQL, args = joinExprs(d, e.order, ", ")
+		sql += " ORDER BY " + orderSQL
+	}
+	return sql + ") AS " + d.QuoteIdentifier(e.alias), args
+}
+
+// load queries the related records of the parent keys and adds them to
+// related by parent key.
+func (p *preloadPlan) load(ctx context.Context, keys [][]interface{}, related map[string][]reflect.Value) error {
+	qb, err := p.query(ctx, keys)
+	if err != nil {
+		return err
+	}
+	rows, err := qb.Execute()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+	indexes := make([]int, len(p.resultColumns))
+	for i, name := range p.resultColumns {
+		indexes[i] = columnIndex(columns, name)
+		if indexes[i] < 0 {
+			return fmt.Errorf("result has no column %s", name)
+		}
+	}
+	scanner := newRowScanner(p.target, columns)
+	track := reflect.PtrTo(p.target.Type).Implements(dirtyTrackerType)
+
+	key := make([]interface{}, len(indexes))
+	for rows.Next() {
+		item := reflect.New(p.target.Type)
+		if err := scanner.scan(rows, item.Elem()); err != nil {
+			return err
+		}
+		if track {
+			snapshotModel(item.Interface())
+		}
+		for i, index := range indexes {
+			key[i] = scanner.raw[index]
+		}
+		s := keyString(key)
+		related[s] = append(related[s], item)
+	}
+	return rows.Err()
+}
+
+// assign sets the association field of a parent to the related records,
+// given as pointers to the target model.
+func (p *preloadPlan) assign(parent reflect.Value, items []reflect.Value) error {
+	field, ok := fieldByIndex(parent, p.rel.Index, true)
+	if !ok || !field.CanSet() {
+		return fmt.Errorf("cannot set association field %s", p.name)
+	}
+	if !p.rel.Many {
+		if len(items) == 0 {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		field.Set(items[0])
+		return nil
+	}
+
+	slice := reflect.MakeSlice(field.Type(), 0, len(items))
+	isPtr := field.Type().Elem().Kind() == reflect.Ptr
+	for _, item := range items {
+		if isPtr {
+			slice = reflect.Append(slice, item)
+		} else {
+			slice = reflect.Append(slice, item.Elem())
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// loaded returns the records set on the association fields of parents,
+// each once.
+func (p *preloadPlan) loaded(parents []reflect.Value) []reflect.Value {
+	var records []reflect.Value
+	seen := make(map[uintptr]bool)
+	add := func(v reflect.Value) {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+		if addr := v.Addr().Pointer(); !seen[addr] {
+			seen[addr] = true
+			records = append(records, v)
+		}
+	}
+	for _, parent := range parents {
+		field, ok := fieldByIndex(parent, p.rel.Index, false)
+		if !ok {
+			continue
+		}
+		if !p.rel.Many {
+			add(field)
+			continue
+		}
+		for i := 0; i < field.Len(); i++ {
+			add(field.Index(i))
+		}
+	}
+	return records
+}
+
+// columnIndex returns the index of a result column, matched exactly or
+// else case-insensitively, or -1.
+func columnIndex(columns []string, name string) int {
+	for i, c := range columns {
+		if c == name {
+			return i
+		}
+	}
+	for i, c := range columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// keyString returns a comparable form of key values, so keys read from the
+// database match the values of model fields of any integer or string type.
+func keyString(key []interface{}) string {
+	parts := make([]string, len(key))
+	for i, v := range key {
+		if c, err := driver.DefaultParameterConverter.ConvertValue(v); err == nil {
+			v = c
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, "\x00")
+}
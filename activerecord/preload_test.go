@@ -0,0 +1,273 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type plAuthor struct {
+	ActiveRecordModel
+	Name    string     `db:"name"`
+	Posts   []*plPost  `ar:"has_many,foreign_key=author_id"`
+	Profile *plProfile `ar:"has_one,foreign_key=AuthorID"`
+	// LatestPost is the author's post with the highest id
+	LatestPost *plPost `ar:"has_one,foreign_key=author_id,order=id DESC"`
+}
+
+func (a *plAuthor) TableName() string { return "pl_authors" }
+
+type plProfile struct {
+	ActiveRecordModel
+	AuthorID int64  `db:"author_id"`
+	Bio      string `db:"bio"`
+}
+
+func (p *plProfile) TableName() string { return "pl_profiles" }
+
+type plPost struct {
+	ActiveRecordModel
+	AuthorID int64  `db:"author_id"`
+	Title    string `db:"title"`
+	Author   *plAuthor
+	Tags     []plTag      `ar:"has_many_through,through=pl_taggings,foreign_key=post_id,local_key=tag_id"`
+	Comments []*plComment `ar:"has_many,foreign_key=post_id"`
+}
+
+func (p *plPost) TableName() string { return "pl_posts" }
+
+type plComment struct {
+	ActiveRecordModel
+	PostID   int64  `db:"post_id"`
+	AuthorID int64  `db:"author_id"`
+	Body     string `db:"body"`
+	Author   *plAuthor
+}
+
+func (c *plComment) TableName() string { return "pl_comments" }
+
+type plTag struct {
+	ActiveRecordModel
+	Name string `db:"name"`
+}
+
+func (t *plTag) TableName() string { return "pl_tags" }
+
+func setupPreloadTestDB(t *testing.T) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	for _, stmt := range []string{
+		`CREATE TABLE pl_authors (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at TIMESTAMP, updated_at TIMESTAMP)`,
+		`CREATE TABLE pl_profiles (id INTEGER PRIMARY KEY AUTOINCREMENT, author_id INTEGER, bio TEXT, created_at TIMESTAMP, updated_at TIMESTAMP)`,
+		`CREATE TABLE pl_posts (id INTEGER PRIMARY KEY AUTOINCREMENT, author_id INTEGER, title TEXT, created_at TIMESTAMP, updated_at TIMESTAMP)`,
+		`CREATE TABLE pl_tags (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at TIMESTAMP, updated_at TIMESTAMP)`,
+		`CREATE TABLE pl_taggings (post_id INTEGER, tag_id INTEGER)`,
+		`CREATE TABLE pl_comments (id INTEGER PRIMARY KEY AUTOINCREMENT, post_id INTEGER, author_id INTEGER, body TEXT, created_at TIMESTAMP, updated_at TIMESTAMP)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+
+	// ann has two posts and a profile, bob has one post, cid has nothing
+	for _, name := range []string{"ann", "bob", "cid"} {
+		if err := Create(&plAuthor{Name: name}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	for _, p := range []*plPost{{AuthorID: 1, Title: "a1"}, {AuthorID: 2, Title: "b1"}, {AuthorID: 1, Title: "a2"}} {
+		if err := Create(p); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if err := Create(&plProfile{AuthorID: 1, Bio: "writer"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for _, name := range []string{"go", "sql"} {
+		if err := Create(&plTag{Name: name}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO pl_taggings (post_id, tag_id) VALUES (1, 1), (1, 2), (2, 2)`); err != nil {
+		t.Fatalf("Failed to tag posts: %v", err)
+	}
+	// bob and cid comment on a1, ann on b1
+	for _, c := range []*plComment{{PostID: 1, AuthorID: 2, Body: "nice"}, {PostID: 1, AuthorID: 3, Body: "meh"}, {PostID: 2, AuthorID: 1, Body: "thanks"}} {
+		if err := Create(c); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+}
+
+func TestQueryBuilderPreload(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var authors []*plAuthor
+	if err := NewQueryBuilder("pl_authors").OrderBy("id", "ASC").Preload("Posts", "Profile").Find(&authors); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(authors) != 3 {
+		t.Fatalf("expected 3 authors, got %d", len(authors))
+	}
+	var titles []string
+	for _, p := range authors[0].Posts {
+		titles = append(titles, p.Title)
+	}
+	if strings.Join(titles, ",") != "a1,a2" || len(authors[1].Posts) != 1 {
+		t.Errorf("unexpected posts: %v, %d", titles, len(authors[1].Posts))
+	}
+	if authors[2].Posts == nil || len(authors[2].Posts) != 0 {
+		t.Errorf("expected an empty slice for an author without posts, got %#v", authors[2].Posts)
+	}
+	if authors[0].Profile == nil || authors[0].Profile.Bio != "writer" || authors[1].Profile != nil {
+		t.Errorf("unexpected profiles: %+v, %+v", authors[0].Profile, authors[1].Profile)
+	}
+
+	var post plPost
+	if err := NewQueryBuilder("pl_posts").Where("title = ?", "b1").Include("Author", "Tags").First(&post); err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if post.Author == nil || post.Author.Name != "bob" {
+		t.Errorf("unexpected author: %+v", post.Author)
+	}
+	if len(post.Tags) != 1 || post.Tags[0].Name != "sql" {
+		t.Errorf("unexpected tags: %+v", post.Tags)
+	}
+}
+
+func TestPreload(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var posts []plPost
+	if err := FindAll(&posts); err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if err := Preload(&posts, "Author", "Tags"); err != nil {
+		t.Fatalf("Preload failed: %v", err)
+	}
+	if posts[0].Author == nil || posts[0].Author != posts[2].Author || posts[1].Author.Name != "bob" {
+		t.Errorf("expected posts of one author to share it: %+v", posts)
+	}
+	if len(posts[0].Tags) != 2 || posts[0].Tags[0].Name != "go" || len(posts[2].Tags) != 0 {
+		t.Errorf("unexpected tags: %+v, %+v", posts[0].Tags, posts[2].Tags)
+	}
+
+	if err := Preload(&posts, "Reviews"); err == nil {
+		t.Error("expected an error for an unknown association")
+	}
+}
+
+func TestPreloadQuery(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	plan, err := newPreloadPlan(reflect.TypeOf(plPost{}), "Tags")
+	if err != nil {
+		t.Fatalf("newPreloadPlan failed: %v", err)
+	}
+	qb, err := plan.query(context.Background(), [][]interface{}{{int64(1)}, {int64(2)}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	got, _ := qb.ToSQL()
+	want := `SELECT "pl_tags".*, "pl_taggings"."post_id" AS "ar_preload_owner" FROM "pl_tags" ` +
+		`JOIN "pl_taggings" ON pl_taggings.tag_id = pl_tags.id WHERE "pl_taggings"."post_id" IN (1, 2) ORDER BY "pl_tags"."id" ASC`
+	if got != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestNestedPreload(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var authors []*plAuthor
+	qb := NewQueryBuilder("pl_authors").OrderBy("id", "ASC").Preload("Posts.Comments.Author", "Posts.Tags")
+	if err := qb.Find(&authors); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	a1 := authors[0].Posts[0]
+	if len(a1.Comments) != 2 || a1.Comments[0].Author == nil || a1.Comments[0].Author.Name != "bob" ||
+		a1.Comments[1].Author.Name != "cid" {
+		t.Fatalf("unexpected comments of a1: %+v", a1.Comments)
+	}
+	if len(a1.Tags) != 2 {
+		t.Errorf("unexpected tags of a1: %+v", a1.Tags)
+	}
+	b1 := authors[1].Posts[0]
+	if len(b1.Comments) != 1 || b1.Comments[0].Author.Name != "ann" {
+		t.Errorf("unexpected comments of b1: %+v", b1.Comments)
+	}
+	if a1.Comments[0].Author.Posts != nil {
+		t.Error("associations outside the path should not be loaded")
+	}
+
+	if err := Preload(&authors, "Posts.Missing"); err == nil {
+		t.Error("expected an error for an unknown nested association")
+	}
+}
+
+func TestPreloadWith(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	var authors []*plAuthor
+	err := NewQueryBuilder("pl_authors").OrderBy("id", "ASC").
+		PreloadWith("Posts", func(q *QueryBuilder) {
+			q.OrderBy("title", "DESC").Limit(1)
+		}).
+		PreloadWith("Posts.Comments", func(q *QueryBuilder) {
+			q.Where("body <> ?", "meh")
+		}).
+		Find(&authors)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(authors[0].Posts) != 1 || authors[0].Posts[0].Title != "a2" {
+		t.Errorf("expected the newest post of ann only, got %+v", authors[0].Posts)
+	}
+	if len(authors[1].Posts) != 1 || authors[1].Posts[0].Title != "b1" || len(authors[2].Posts) != 0 {
+		t.Errorf("unexpected posts: %+v, %+v", authors[1].Posts, authors[2].Posts)
+	}
+	if c := authors[1].Posts[0].Comments; len(c) != 1 || c[0].Body != "thanks" {
+		t.Errorf("unexpected comments: %+v", c)
+	}
+
+	var posts []*plPost
+	err = NewQueryBuilder("pl_posts").OrderBy("id", "ASC").
+		PreloadWith("Comments", func(q *QueryBuilder) { q.Where("body <> ?", "meh") }).
+		Find(&posts)
+	if err != nil || len(posts[0].Comments) != 1 || posts[0].Comments[0].Body != "nice" {
+		t.Errorf("unexpected filtered comments: %v, %+v", err, posts[0].Comments)
+	}
+
+	err = NewQueryBuilder("pl_authors").
+		PreloadWith("Posts", func(q *QueryBuilder) { q.OrderBy("title", "sideways") }).
+		Find(&authors)
+	if !errors.Is(err, ErrInvalidDirection) {
+		t.Errorf("expected ErrInvalidDirection, got %v", err)
+	}
+}
+
+func TestPreloadLimitPerParentQuery(t *testing.T) {
+	setupPreloadTestDB(t)
+
+	plan, err := newPreloadPlan(reflect.TypeOf(plAuthor{}), "Posts")
+	if err != nil {
+		t.Fatalf("newPreloadPlan failed: %v", err)
+	}
+	plan.fns = []func(*QueryBuilder){func(q *QueryBuilder) { q.OrderBy("title", "DESC").Limit(2).Offset(1) }}
+	qb, err := plan.query(context.Background(), [][]interface{}{{int64(1)}, {int64(2)}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	got, _ := qb.ToSQL()
+	want := `WITH "ar_preload_ranked" AS (SELECT "pl_posts".*, ROW_NUMBER() OVER (PARTITION BY "pl_posts"."author_id" ` +
+		`ORDER BY "title" DESC, "pl_posts"."id" ASC) AS "ar_preload_rank" FROM "pl_posts" WHERE "pl_posts"."author_id" IN (1, 2)) ` +
+		`SELECT * FROM "ar_preload_ranked" WHERE "ar_preload_rank" > 1 AND "ar_preload_rank" <= 3 ORDER BY "ar_preload_rank" ASC`
+	if got != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", got, want)
+	}
+}
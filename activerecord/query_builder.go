@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"time"
 )
 
 // QueryMode represents the mode of query execution
@@ -15,44 +14,57 @@ type QueryMode int
 const (
 	NormalMode QueryMode = iota
 	PreparedStatementMode
+	// DryRunMode records statements instead of executing them; see
+	// QueryBuilder.DryRun
 	DryRunMode
 )
 
 // QueryBuilder represents a query builder
 type QueryBuilder struct {
 	tableName    string
-	selectFields []string
-	whereClauses []string
-	whereArgs    []interface{}
-	joins        []string
-	orderBy      []string
-	groupBy      []string
-	having       []string
+	selectFields []Expr
+	where        []Expr
+	joins        []Expr
+	orderBy      []Expr
+	groupBy      []Expr
+	having       []Expr
 	limit        int
 	offset       int
 	distinct     bool
 	lock         string
 	hints        []string
 	mode         QueryMode
+	collector    *DryRunCollector
 	ctx          context.Context
-	preloads     []string
+	preloads     []preloadSpec
 	includes     []string
 	excludes     []string
+	dialect      Dialect
+	model        reflect.Type
+	unscoped     bool
+	// defaultScoped is set once the default scope has been applied
+	defaultScoped bool
+	ctes          []cte
+	recursive     bool
+	compounds     []compound
+	keyset        []keysetColumn
+	cursor        []interface{}
+	cursorBefore  bool
+	err           error
 }
 
 // NewQueryBuilder creates a new query builder
 func NewQueryBuilder(tableName string) *QueryBuilder {
 	return &QueryBuilder{
 		tableName:    tableName,
-		selectFields: []string{"*"},
-		whereClauses: make([]string, 0),
-		whereArgs:    make([]interface{}, 0),
-		joins:        make([]string, 0),
-		orderBy:      make([]string, 0),
-		groupBy:      make([]string, 0),
-		having:       make([]string, 0),
+		selectFields: []Expr{Raw("*")},
+		where:        make([]Expr, 0),
+		joins:        make([]Expr, 0),
+		orderBy:      make([]Expr, 0),
+		groupBy:      make([]Expr, 0),
+		having:       make([]Expr, 0),
 		hints:        make([]string, 0),
-		preloads:     make([]string, 0),
+		preloads:     make([]preloadSpec, 0),
 		includes:     make([]string, 0),
 		excludes:     make([]string, 0),
 		mode:         NormalMode,
@@ -60,31 +72,86 @@ func NewQueryBuilder(tableName string) *QueryBuilder {
 	}
 }
 
-// Select sets the fields to select
+// Select sets the fields to select. A column, optionally qualified and
+// aliased as in "users.name AS author", is quoted through the dialect and
+// checked against the model. Any other field, such as "COUNT(*)",
+// "DISTINCT name" or "SUM(amount) AS total", is selected as written, so it
+// must not come from user input.
 func (qb *QueryBuilder) Select(fields ...string) *QueryBuilder {
-	qb.selectFields = fields
+	qb.selectFields = make([]Expr, 0, len(fields))
+	for _, field := range fields {
+		if column, ok := selectColumn(field); ok {
+			qb.selectFields = append(qb.selectFields, column)
+			continue
+		}
+		qb.selectFields = append(qb.selectFields, Raw(field))
+	}
+	return qb
+}
+
+// SelectExpr sets the fields to select as expressions
+//
+//	qb.SelectExpr(Raw("status"), Raw("SUM(amount) AS total"))
+func (qb *QueryBuilder) SelectExpr(exprs ...Expr) *QueryBuilder {
+	qb.selectFields = append([]Expr{}, exprs...)
 	return qb
 }
 
 // Where adds a where clause
 func (qb *QueryBuilder) Where(condition string, args ...interface{}) *QueryBuilder {
-	qb.whereClauses = append(qb.whereClauses, condition)
-	qb.whereArgs = append(qb.whereArgs, args...)
+	qb.where = append(qb.where, Raw(condition, args...))
+	return qb
+}
+
+// WhereExpr adds where clauses built from expressions
+func (qb *QueryBuilder) WhereExpr(exprs ...Expr) *QueryBuilder {
+	qb.where = append(qb.where, exprs...)
+	return qb
+}
+
+// OrWhere adds a where clause matched as an alternative to all previous ones
+func (qb *QueryBuilder) OrWhere(condition string, args ...interface{}) *QueryBuilder {
+	if len(qb.where) == 0 {
+		return qb.Where(condition, args...)
+	}
+	qb.where = []Expr{Or(And(qb.where...), Raw(condition, args...))}
+	return qb
+}
+
+// WhereNot adds a negated where clause
+func (qb *QueryBuilder) WhereNot(condition string, args ...interface{}) *QueryBuilder {
+	qb.where = append(qb.where, Not(Raw(condition, args...)))
+	return qb
+}
+
+// WhereGroup adds the where clauses added by fn as one parenthesized clause
+//
+//	qb.WhereGroup(func(g *QueryBuilder) {
+//		g.Where("a = ?", 1).OrWhere("b = ?", 2)
+//	}).WhereNot("c")
+func (qb *QueryBuilder) WhereGroup(fn func(*QueryBuilder)) *QueryBuilder {
+	group := &QueryBuilder{tableName: qb.tableName, dialect: qb.dialect, model: qb.model, ctx: qb.ctx}
+	fn(group)
+	if group.err != nil {
+		return qb.fail(group.err)
+	}
+	qb.where = append(qb.where, And(group.where...))
 	return qb
 }
 
-// WhereIn adds a where in clause
+// WhereIn adds a where in clause. A single *QueryBuilder value is used as a
+// subquery.
 func (qb *QueryBuilder) WhereIn(field string, values []interface{}) *QueryBuilder {
+	if len(values) == 1 {
+		if sub, ok := values[0].(*QueryBuilder); ok {
+			return qb.WhereExpr(Raw(field+" IN ?", sub))
+		}
+	}
 	if len(values) == 0 {
 		return qb.Where("1 = 0") // Always false
 	}
 
-	placeholders := make([]string, len(values))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
-	condition := fmt.Sprintf("%s IN (%s)", field, strings.Join(placeholders, ", "))
+	condition := fmt.Sprintf("%s IN (%s)", field, placeholders(len(values)))
 	return qb.Where(condition, values...)
 }
 
@@ -94,12 +161,7 @@ func (qb *QueryBuilder) WhereNotIn(field string, values []interface{}) *QueryBui
 		return qb
 	}
 
-	placeholders := make([]string, len(values))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
-	condition := fmt.Sprintf("%s NOT IN (%s)", field, strings.Join(placeholders, ", "))
+	condition := fmt.Sprintf("%s NOT IN (%s)", field, placeholders(len(values)))
 	return qb.Where(condition, values...)
 }
 
@@ -113,51 +175,101 @@ func (qb *QueryBuilder) WhereNotNull(field string) *QueryBuilder {
 	return qb.Where(fmt.Sprintf("%s IS NOT NULL", field))
 }
 
-// Join adds a join clause
+// Join adds a join clause. The table, optionally aliased as in "users u",
+// is quoted through the dialect; the condition is SQL, like Where's.
 func (qb *QueryBuilder) Join(table, condition string) *QueryBuilder {
-	join := fmt.Sprintf("JOIN %s ON %s", table, condition)
-	qb.joins = append(qb.joins, join)
-	return qb
+	return qb.join("JOIN", table, condition)
 }
 
 // LeftJoin adds a left join clause
 func (qb *QueryBuilder) LeftJoin(table, condition string) *QueryBuilder {
-	join := fmt.Sprintf("LEFT JOIN %s ON %s", table, condition)
-	qb.joins = append(qb.joins, join)
-	return qb
+	return qb.join("LEFT JOIN", table, condition)
 }
 
 // RightJoin adds a right join clause
 func (qb *QueryBuilder) RightJoin(table, condition string) *QueryBuilder {
-	join := fmt.Sprintf("RIGHT JOIN %s ON %s", table, condition)
-	qb.joins = append(qb.joins, join)
-	return qb
+	return qb.join("RIGHT JOIN", table, condition)
 }
 
 // InnerJoin adds an inner join clause
 func (qb *QueryBuilder) InnerJoin(table, condition string) *QueryBuilder {
-	join := fmt.Sprintf("INNER JOIN %s ON %s", table, condition)
-	qb.joins = append(qb.joins, join)
+	return qb.join("INNER JOIN", table, condition)
+}
+
+func (qb *QueryBuilder) join(kind, table, condition string) *QueryBuilder {
+	t, err := parseColumn(table)
+	if err != nil || t.name == "*" {
+		return qb.fail(fmt.Errorf("%w: %q", ErrInvalidIdentifier, table))
+	}
+	qb.joins = append(qb.joins, joinExpr{kind: kind, table: t, condition: condition})
+	return qb
+}
+
+// joinExpr is a join with a quoted table and a condition written as SQL.
+type joinExpr struct {
+	kind      string
+	table     columnExpr
+	condition string
+}
+
+func (e joinExpr) SQL(d Dialect) (string, []interface{}) {
+	table, _ := e.table.SQL(d)
+	return e.kind + " " + table + " ON " + e.condition, nil
+}
+
+// JoinExpr adds join clauses written as SQL
+//
+//	qb.JoinExpr(Raw("JOIN orders o ON o.user_id = users.id AND o.total > ?", 100))
+func (qb *QueryBuilder) JoinExpr(exprs ...Expr) *QueryBuilder {
+	qb.joins = append(qb.joins, exprs...)
 	return qb
 }
 
-// OrderBy adds an order by clause
+// OrderBy adds an order by clause. The field is a column name, quoted
+// through the dialect, and the direction is ASC or DESC, optionally
+// followed by NULLS FIRST or NULLS LAST. Both are validated, so they may
+// come from user input. Use OrderByExpr for expressions.
 func (qb *QueryBuilder) OrderBy(field, direction string) *QueryBuilder {
-	order := fmt.Sprintf("%s %s", field, strings.ToUpper(direction))
-	qb.orderBy = append(qb.orderBy, order)
+	desc, nulls, err := parseDirection(direction)
+	if err == nil {
+		err = checkIdentifier(field)
+	}
+	if err != nil {
+		return qb.fail(err)
+	}
+	qb.orderBy = append(qb.orderBy, orderExpr{column: field, desc: desc, nulls: nulls})
+	return qb
+}
+
+// OrderByExpr adds order by clauses written as SQL
+//
+//	qb.OrderByExpr(Raw("LENGTH(title) DESC"))
+func (qb *QueryBuilder) OrderByExpr(exprs ...Expr) *QueryBuilder {
+	qb.orderBy = append(qb.orderBy, exprs...)
 	return qb
 }
 
-// GroupBy adds a group by clause
+// GroupBy adds group by columns, quoted through the dialect. Use
+// GroupByExpr for expressions.
 func (qb *QueryBuilder) GroupBy(fields ...string) *QueryBuilder {
-	qb.groupBy = append(qb.groupBy, fields...)
+	for _, field := range fields {
+		if err := checkIdentifier(field); err != nil {
+			return qb.fail(err)
+		}
+		qb.groupBy = append(qb.groupBy, columnExpr{name: field})
+	}
+	return qb
+}
+
+// GroupByExpr adds group by clauses written as SQL
+func (qb *QueryBuilder) GroupByExpr(exprs ...Expr) *QueryBuilder {
+	qb.groupBy = append(qb.groupBy, exprs...)
 	return qb
 }
 
 // Having adds a having clause
 func (qb *QueryBuilder) Having(condition string, args ...interface{}) *QueryBuilder {
-	qb.having = append(qb.having, condition)
-	qb.whereArgs = append(qb.whereArgs, args...)
+	qb.having = append(qb.having, Raw(condition, args...))
 	return qb
 }
 
@@ -197,19 +309,105 @@ func (qb *QueryBuilder) SetMode(mode QueryMode) *QueryBuilder {
 	return qb
 }
 
+// SetDialect sets the dialect used by Build.
+// By default the dialect of the current connection is used.
+func (qb *QueryBuilder) SetDialect(dialect Dialect) *QueryBuilder {
+	qb.dialect = dialect
+	return qb
+}
+
+// getDialect returns the builder's dialect or the connection's dialect
+func (qb *QueryBuilder) getDialect() Dialect {
+	if qb.dialect != nil {
+		return qb.dialect
+	}
+	return GetDialect()
+}
+
+// Unscoped includes soft-deleted rows in the results and skips the default
+// scope
+func (qb *QueryBuilder) Unscoped() *QueryBuilder {
+	qb.unscoped = true
+	return qb
+}
+
+// softDeleteScope returns the condition excluding soft-deleted rows, using
+// the schema of the scanned model or else of the model registered for the
+// table
+func (qb *QueryBuilder) softDeleteScope(d Dialect) string {
+	if qb.unscoped {
+		return ""
+	}
+	return notDeleted(qb.ctx, d, qb.tableName, qb.softDeleteSchema())
+}
+
+// softDeleteSchema returns the schema of the query's model or of the model
+// registered for the table, if any.
+func (qb *QueryBuilder) softDeleteSchema() *ModelSchema {
+	if qb.model != nil {
+		return schemaFor(qb.model)
+	}
+	if t := registeredModel(qb.tableName); t != nil {
+		return schemaFor(t)
+	}
+	return nil
+}
+
+// Model sets the model of the query, a pointer to one or a slice of models,
+// whose soft-deleted rows are excluded from queries that scan no model,
+// such as Count and DeleteAll.
+func (qb *QueryBuilder) Model(model interface{}) *QueryBuilder {
+	t := modelStructType(model)
+	if t == nil {
+		return qb.fail(fmt.Errorf("model must be a struct or a pointer to a struct, got %T", model))
+	}
+	qb.model = t
+	return qb
+}
+
+// whereClause renders the WHERE conditions, including the soft-delete scope
+// and the cursor condition.
+func (qb *QueryBuilder) whereClause(d Dialect) (string, []interface{}) {
+	where := qb.where
+	if scope := qb.softDeleteScope(d); scope != "" {
+		where = append(where[:len(where):len(where)], Raw(scope))
+	}
+	if cursor := qb.cursorCondition(d); cursor != nil {
+		where = append(where[:len(where):len(where)], cursor)
+	}
+	return And(where...).SQL(d)
+}
+
 // WithContext sets the context
 func (qb *QueryBuilder) WithContext(ctx context.Context) *QueryBuilder {
 	qb.ctx = ctx
 	return qb
 }
 
-// Preload adds preload associations
+// Preload adds associations loaded by Find and First, with one query per
+// association for all the rows found. Dotted paths load nested
+// associations.
+//
+//	qb.Preload("Posts.Comments.Author", "Profile").Find(&users)
 func (qb *QueryBuilder) Preload(associations ...string) *QueryBuilder {
-	qb.preloads = append(qb.preloads, associations...)
+	for _, path := range associations {
+		qb.preloads = append(qb.preloads, preloadSpec{path: path})
+	}
 	return qb
 }
 
-// Include adds include associations
+// PreloadWith adds an association to preload whose query is customized by
+// fn. A limit or offset set by fn applies to the records of each parent.
+//
+//	qb.PreloadWith("Posts", func(q *QueryBuilder) {
+//		q.Where("published = ?", true).OrderBy("created_at", "DESC").Limit(3)
+//	})
+func (qb *QueryBuilder) PreloadWith(association string, fn func(*QueryBuilder)) *QueryBuilder {
+	qb.preloads = append(qb.preloads, preloadSpec{path: association, fn: fn})
+	return qb
+}
+
+// Include adds associations to preload, like Preload
 func (qb *QueryBuilder) Include(associations ...string) *QueryBuilder {
 	qb.includes = append(qb.includes, associations...)
 	return qb
@@ -221,9 +419,31 @@ func (qb *QueryBuilder) Exclude(fields ...string) *QueryBuilder {
 	return qb
 }
 
-// Build builds the SQL query
+// Build builds the SQL query with placeholders in the dialect's style
 func (qb *QueryBuilder) Build() (string, []interface{}) {
+	query, args := qb.build()
+	return Rebind(qb.getDialect(), query), args
+}
+
+// build builds the SQL query with "?" placeholders
+func (qb *QueryBuilder) build() (string, []interface{}) {
+	return qb.buildWith(qb.getDialect())
+}
+
+// buildWith builds the SQL query with "?" placeholders for a dialect, which
+// is the outer query's when qb is a subquery
+func (qb *QueryBuilder) buildWith(d Dialect) (string, []interface{}) {
+	qb = qb.withDefaultScope()
 	var query strings.Builder
+	var args []interface{}
+
+	// WITH
+	if len(qb.ctes) > 0 {
+		withSQL, withArgs := qb.withClause(d)
+		query.WriteString(withSQL)
+		query.WriteString(" ")
+		args = append(args, withArgs...)
+	}
 
 	// Add hints if any
 	if len(qb.hints) > 0 {
@@ -235,50 +455,64 @@ func (qb *QueryBuilder) Build() (string, []interface{}) {
 	if qb.distinct {
 		query.WriteString("DISTINCT ")
 	}
-	query.WriteString(strings.Join(qb.selectFields, ", "))
+	selectSQL, selectArgs := joinExprs(d, qb.selectFields, ", ")
+	query.WriteString(selectSQL)
+	args = append(args, selectArgs...)
 
 	// FROM
 	query.WriteString(" FROM ")
-	query.WriteString(qb.tableName)
+	query.WriteString(quoteTable(d, qb.tableName))
 
 	// JOINS
 	if len(qb.joins) > 0 {
+		joinSQL, joinArgs := joinExprs(d, qb.joins, " ")
 		query.WriteString(" ")
-		query.WriteString(strings.Join(qb.joins, " "))
+		query.WriteString(joinSQL)
+		args = append(args, joinArgs...)
 	}
 
 	// WHERE
-	if len(qb.whereClauses) > 0 {
+	if whereSQL, whereArgs := qb.whereClause(d); whereSQL != "" {
 		query.WriteString(" WHERE ")
-		query.WriteString(strings.Join(qb.whereClauses, " AND "))
+		query.WriteString(whereSQL)
+		args = append(args, whereArgs...)
 	}
 
 	// GROUP BY
 	if len(qb.groupBy) > 0 {
+		groupSQL, groupArgs := joinExprs(d, qb.groupBy, ", ")
 		query.WriteString(" GROUP BY ")
-		query.WriteString(strings.Join(qb.groupBy, ", "))
+		query.WriteString(groupSQL)
+		args = append(args, groupArgs...)
 	}
 
 	// HAVING
-	if len(qb.having) > 0 {
+	if havingSQL, havingArgs := And(qb.having...).SQL(d); havingSQL != "" {
 		query.WriteString(" HAVING ")
-		query.WriteString(strings.Join(qb.having, " AND "))
+		query.WriteString(havingSQL)
+		args = append(args, havingArgs...)
 	}
 
-	// ORDER BY
-	if len(qb.orderBy) > 0 {
-		query.WriteString(" ORDER BY ")
-		query.WriteString(strings.Join(qb.orderBy, ", "))
+	// UNION / INTERSECT / EXCEPT
+	for i, c := range qb.compounds {
+		compoundSQL, compoundArgs := c.operandSQL(d, fmt.Sprintf("compound_%d", i+1))
+		query.WriteString(" " + c.op + " ")
+		query.WriteString(compoundSQL)
+		args = append(args, compoundArgs...)
 	}
 
-	// LIMIT
-	if qb.limit > 0 {
-		query.WriteString(fmt.Sprintf(" LIMIT %d", qb.limit))
+	// ORDER BY
+	if orderBy := append(qb.keysetOrder(), qb.orderBy...); len(orderBy) > 0 {
+		orderSQL, orderArgs := joinExprs(d, orderBy, ", ")
+		query.WriteString(" ORDER BY ")
+		query.WriteString(orderSQL)
+		args = append(args, orderArgs...)
 	}
 
-	// OFFSET
-	if qb.offset > 0 {
-		query.WriteString(fmt.Sprintf(" OFFSET %d", qb.offset))
+	// LIMIT / OFFSET
+	if clause := d.LimitOffset(qb.limit, qb.offset); clause != "" {
+		query.WriteString(" ")
+		query.WriteString(clause)
 	}
 
 	// LOCK
@@ -287,16 +521,19 @@ func (qb *QueryBuilder) Build() (string, []interface{}) {
 		query.WriteString(qb.lock)
 	}
 
-	return query.String(), qb.whereArgs
+	return query.String(), args
 }
 
 // Execute executes the query and returns rows
 func (qb *QueryBuilder) Execute() (*sql.Rows, error) {
+	if err := qb.validate(); err != nil {
+		return nil, err
+	}
 	query, args := qb.Build()
 
-	if qb.mode == DryRunMode {
-		fmt.Printf("DRY RUN - Query: %s, Args: %v\n", query, args)
-		return nil, nil
+	if c, ok := qb.dryRun(); ok {
+		c.record(qb.getDialect(), query, args)
+		return nil, ErrDryRun
 	}
 
 	if qb.mode == PreparedStatementMode {
@@ -313,56 +550,67 @@ func (qb *QueryBuilder) Execute() (*sql.Rows, error) {
 
 // Find executes the query and scans results into the provided slice
 func (qb *QueryBuilder) Find(models interface{}) error {
+	if qb.model == nil {
+		qb.model = modelStructType(models)
+	}
 	rows, err := qb.Execute()
 	if err != nil {
 		return err
 	}
-	if rows == nil {
-		return nil // Dry run mode
-	}
 	defer rows.Close()
 
-	return scanRows(rows, models)
+	// Rows are appended to models, which may hold records already
+	appended := sliceLen(models)
+	if err := scanRows(rows, models); err != nil {
+		return err
+	}
+	if qb.cursorBefore {
+		reverseSlice(models, appended)
+	}
+	return qb.preload(models)
 }
 
 // First executes the query and returns the first result
 func (qb *QueryBuilder) First(model interface{}) error {
+	if qb.model == nil {
+		qb.model = modelStructType(model)
+	}
 	qb.Limit(1)
 	rows, err := qb.Execute()
 	if err != nil {
 		return err
 	}
-	if rows == nil {
-		return nil // Dry run mode
-	}
 	defer rows.Close()
 
 	if !rows.Next() {
 		return ErrNotFound
 	}
+	if err := scanRow(rows, model); err != nil {
+		return err
+	}
+	rows.Close()
+	return qb.preload(model)
+}
 
-	return scanRow(rows, model)
+// preload loads the associations added by Preload and Include into models.
+func (qb *QueryBuilder) preload(models interface{}) error {
+	if len(qb.preloads) == 0 && len(qb.includes) == 0 {
+		return nil
+	}
+	specs := append([]preloadSpec{}, qb.preloads...)
+	for _, path := range qb.includes {
+		specs = append(specs, preloadSpec{path: path})
+	}
+	return preload(qb.ctx, models, specs)
 }
 
 // Count executes a count query
 func (qb *QueryBuilder) Count() (int64, error) {
-	originalSelect := qb.selectFields
-	qb.selectFields = []string{"COUNT(*)"}
-
-	query, args := qb.Build()
-
-	if qb.mode == DryRunMode {
-		fmt.Printf("DRY RUN - Count Query: %s, Args: %v\n", query, args)
-		return 0, nil
+	var count sql.NullInt64
+	if err := qb.aggregate("COUNT(*)", &count); err != nil {
+		return 0, err
 	}
-
-	var count int64
-	err := GetConnection().QueryRowContext(qb.ctx, query, args...).Scan(&count)
-
-	// Restore original select fields
-	qb.selectFields = originalSelect
-
-	return count, err
+	return count.Int64, nil
 }
 
 // Exists checks if any records exist
@@ -372,9 +620,6 @@ func (qb *QueryBuilder) Exists() (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if rows == nil {
-		return false, nil // Dry run mode
-	}
 	defer rows.Close()
 
 	return rows.Next(), nil
@@ -382,53 +627,62 @@ func (qb *QueryBuilder) Exists() (bool, error) {
 
 // Pluck executes the query and returns a slice of values from a single column
 func (qb *QueryBuilder) Pluck(column string, values interface{}) error {
-	originalSelect := qb.selectFields
-	qb.selectFields = []string{column}
+	if err := checkIdentifier(column); err != nil {
+		return err
+	}
+	q := qb.clone()
+	q.selectFields = []Expr{columnExpr{name: column}}
 
-	rows, err := qb.Execute()
+	rows, err := q.Execute()
 	if err != nil {
 		return err
 	}
-	if rows == nil {
-		return nil // Dry run mode
-	}
 	defer rows.Close()
 
-	// Restore original select fields
-	qb.selectFields = originalSelect
-
 	return scanColumn(rows, values)
 }
 
 // Batch processing methods
 
-// FindInBatches processes records in batches
+// FindInBatches processes records in batches, paging by keyset rather than
+// offset so rows changed mid-iteration are neither skipped nor repeated.
+// Batches hold model pointers when the model type is known, and maps of
+// column values otherwise.
 func (qb *QueryBuilder) FindInBatches(batchSize int, fn func([]interface{}) error) error {
-	offset := 0
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
 
+	var after []interface{}
 	for {
 		batchQB := qb.clone()
-		batchQB.Limit(batchSize).Offset(offset)
+		batchQB.keyset = qb.keysetColumns()
+		batchQB.Limit(batchSize)
+		if after != nil {
+			batchQB.cursor = after
+			batchQB.cursorBefore = false
+			batchQB.offset = 0
+		}
 
-		var batch []interface{}
-		err := batchQB.Find(&batch)
+		batch, err := batchQB.findBatch()
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to find batch: %w", err)
 		}
-
 		if len(batch) == 0 {
 			break
 		}
 
 		if err := fn(batch); err != nil {
-			return err
+			return fmt.Errorf("batch callback failed: %w", err)
 		}
 
 		if len(batch) < batchSize {
 			break
 		}
 
-		offset += batchSize
+		if after, err = batchQB.keyValues(batch[len(batch)-1]); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -437,24 +691,35 @@ func (qb *QueryBuilder) FindInBatches(batchSize int, fn func([]interface{}) erro
 // clone creates a copy of the query builder
 func (qb *QueryBuilder) clone() *QueryBuilder {
 	return &QueryBuilder{
-		tableName:    qb.tableName,
-		selectFields: append([]string{}, qb.selectFields...),
-		whereClauses: append([]string{}, qb.whereClauses...),
-		whereArgs:    append([]interface{}{}, qb.whereArgs...),
-		joins:        append([]string{}, qb.joins...),
-		orderBy:      append([]string{}, qb.orderBy...),
-		groupBy:      append([]string{}, qb.groupBy...),
-		having:       append([]string{}, qb.having...),
-		limit:        qb.limit,
-		offset:       qb.offset,
-		distinct:     qb.distinct,
-		lock:         qb.lock,
-		hints:        append([]string{}, qb.hints...),
-		mode:         qb.mode,
-		ctx:          qb.ctx,
-		preloads:     append([]string{}, qb.preloads...),
-		includes:     append([]string{}, qb.includes...),
-		excludes:     append([]string{}, qb.excludes...),
+		tableName:     qb.tableName,
+		selectFields:  append([]Expr{}, qb.selectFields...),
+		where:         append([]Expr{}, qb.where...),
+		joins:         append([]Expr{}, qb.joins...),
+		orderBy:       append([]Expr{}, qb.orderBy...),
+		groupBy:       append([]Expr{}, qb.groupBy...),
+		having:        append([]Expr{}, qb.having...),
+		limit:         qb.limit,
+		offset:        qb.offset,
+		distinct:      qb.distinct,
+		lock:          qb.lock,
+		hints:         append([]string{}, qb.hints...),
+		mode:          qb.mode,
+		collector:     qb.collector,
+		ctx:           qb.ctx,
+		preloads:      append([]preloadSpec{}, qb.preloads...),
+		includes:      append([]string{}, qb.includes...),
+		excludes:      append([]string{}, qb.excludes...),
+		dialect:       qb.dialect,
+		model:         qb.model,
+		unscoped:      qb.unscoped,
+		defaultScoped: qb.defaultScoped,
+		ctes:          append([]cte{}, qb.ctes...),
+		recursive:     qb.recursive,
+		compounds:     append([]compound{}, qb.compounds...),
+		keyset:        append([]keysetColumn{}, qb.keyset...),
+		cursor:        qb.cursor,
+		cursorBefore:  qb.cursorBefore,
+		err:           qb.err,
 	}
 }
 
@@ -474,12 +739,25 @@ func scanRows(rows *sql.Rows, models interface{}) error {
 		structType = elementType.Elem()
 	}
 
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("models must be a slice of structs")
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+	scanner := newRowScanner(schemaFor(structType), columns)
+	track := isPtr && reflect.PtrTo(structType).Implements(dirtyTrackerType)
+
 	for rows.Next() {
 		elementPtr := reflect.New(structType) // always a pointer to struct
-		fmt.Printf("[DEBUG] scanRows: passing type %T to scanRow\n", elementPtr.Interface())
-		if err := scanRow(rows, elementPtr.Interface()); err != nil {
+		if err := scanner.scan(rows, elementPtr.Elem()); err != nil {
 			return err
 		}
+		if track {
+			snapshotModel(elementPtr.Interface())
+		}
 		if isPtr {
 			slice.Set(reflect.Append(slice, elementPtr))
 		} else {
@@ -531,54 +809,17 @@ func mapRowToModel(columns []string, values []interface{}, model interface{}) er
 }
 
 func findFieldByTag(val reflect.Value, typ reflect.Type, tag string) reflect.Value {
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldType := typ.Field(i)
-
-		dbTag := fieldType.Tag.Get("db")
-		if dbTag == tag {
-			return field
-		}
-
-		// Check embedded structs
-		if fieldType.Anonymous && field.Kind() == reflect.Struct {
-			if nestedField := findFieldByTag(field, field.Type(), tag); nestedField.IsValid() {
-				return nestedField
-			}
-		}
+	f, ok := schemaFor(typ).FieldByColumn(tag)
+	if !ok {
+		return reflect.Value{}
 	}
-
-	return reflect.Value{}
+	field, ok := fieldByIndex(val, f.Index, true)
+	if !ok {
+		return reflect.Value{}
+	}
+	return field
 }
 
 func setFieldValue(field reflect.Value, value interface{}) error {
-	if value == nil {
-		field.Set(reflect.Zero(field.Type()))
-		return nil
-	}
-
-	val := reflect.ValueOf(value)
-
-	// Handle time.Time conversion
-	if field.Type() == reflect.TypeOf(time.Time{}) {
-		if timeVal, ok := value.(time.Time); ok {
-			field.Set(reflect.ValueOf(timeVal))
-			return nil
-		}
-		// Try to parse string as time
-		if strVal, ok := value.(string); ok {
-			if t, err := time.Parse(time.RFC3339, strVal); err == nil {
-				field.Set(reflect.ValueOf(t))
-				return nil
-			}
-		}
-	}
-
-	// Handle basic type conversions
-	if val.Type().ConvertibleTo(field.Type()) {
-		field.Set(val.Convert(field.Type()))
-		return nil
-	}
-
-	return fmt.Errorf("cannot convert %v to %v", val.Type(), field.Type())
+	return assignValue(GetDialect(), field, value)
 }
@@ -0,0 +1,105 @@
+package activerecord
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// ModelPtr is satisfied by *T when *T implements Modeler.
+type ModelPtr[T any] interface {
+	*T
+	Modeler
+}
+
+// Repo is a typed repository for models of type T.
+//
+//	users := NewRepo[User]()
+//	user, err := users.Find(ctx, 1)
+type Repo[T any, PT ModelPtr[T]] struct{}
+
+// NewRepo creates a new typed repository
+func NewRepo[T any, PT ModelPtr[T]]() *Repo[T, PT] {
+	return &Repo[T, PT]{}
+}
+
+// TableName returns the table name of the repository's model
+func (r *Repo[T, PT]) TableName() string {
+	return PT(new(T)).TableName()
+}
+
+// Query returns a query builder for the repository's table
+func (r *Repo[T, PT]) Query() *QueryBuilder {
+	qb := NewQueryBuilder(r.TableName())
+	qb.model = reflect.TypeOf(new(T)).Elem()
+	return qb
+}
+
+// Find finds a record by ID
+func (r *Repo[T, PT]) Find(ctx context.Context, id interface{}) (*T, error) {
+	model := new(T)
+	if err := FindWithContext(ctx, PT(model), id); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// Where returns all records matching the query
+func (r *Repo[T, PT]) Where(ctx context.Context, query string, args ...interface{}) ([]*T, error) {
+	var models []*T
+	if err := WhereWithContext(ctx, &models, query, args...); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// First returns the first record matching the query ordered by primary key.
+// An empty query matches all records.
+func (r *Repo[T, PT]) First(ctx context.Context, query string, args ...interface{}) (*T, error) {
+	qb := r.Query().WithContext(ctx)
+	for _, column := range primaryKeyColumns(new(T)) {
+		qb.OrderBy(column, "ASC")
+	}
+	if query != "" {
+		qb.Where(query, args...)
+	}
+
+	model := new(T)
+	if err := qb.First(model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// All returns all records
+func (r *Repo[T, PT]) All(ctx context.Context) ([]*T, error) {
+	var models []*T
+	if err := FindAllWithContext(ctx, &models); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// Create inserts a new record
+func (r *Repo[T, PT]) Create(ctx context.Context, model *T) error {
+	if model == nil {
+		return fmt.Errorf("cannot create nil model")
+	}
+	return CreateWithContext(ctx, PT(model))
+}
+
+// Update updates an existing record
+func (r *Repo[T, PT]) Update(ctx context.Context, model *T) error {
+	if model == nil {
+		return fmt.Errorf("cannot update nil model")
+	}
+	return UpdateWithContext(ctx, PT(model))
+}
+
+// Delete deletes a record
+func (r *Repo[T, PT]) Delete(ctx context.Context, model *T) error {
+	if model == nil {
+		return fmt.Errorf("cannot delete nil model")
+	}
+	return DeleteWithContext(ctx, PT(model))
+}
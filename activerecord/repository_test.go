@@ -0,0 +1,92 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func setupRepoTestDB(t *testing.T) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	if _, err := db.Exec(`CREATE TABLE model_tests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT,
+		age INTEGER,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+}
+
+func TestRepo_CRUD(t *testing.T) {
+	setupRepoTestDB(t)
+	ctx := context.Background()
+	repo := NewRepo[ModelTest]()
+
+	if repo.TableName() != "model_tests" {
+		t.Fatalf("unexpected table name %s", repo.TableName())
+	}
+
+	alice := &ModelTest{Name: "Alice", Age: 30}
+	bob := &ModelTest{Name: "Bob", Age: 20}
+	for _, m := range []*ModelTest{alice, bob} {
+		if err := repo.Create(ctx, m); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	found, err := repo.Find(ctx, alice.GetID())
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if found.Name != "Alice" {
+		t.Errorf("expected Alice, got %s", found.Name)
+	}
+
+	found.Age = 31
+	if err := repo.Update(ctx, found); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	adults, err := repo.Where(ctx, "age > ?", 25)
+	if err != nil {
+		t.Fatalf("Where failed: %v", err)
+	}
+	if len(adults) != 1 || adults[0].Age != 31 {
+		t.Errorf("unexpected Where result: %+v", adults)
+	}
+
+	first, err := repo.First(ctx, "")
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if first.Name != "Alice" {
+		t.Errorf("expected first record Alice, got %s", first.Name)
+	}
+
+	if err := repo.Delete(ctx, bob); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	all, err := repo.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("expected 1 record after delete, got %d", len(all))
+	}
+
+	if _, err := repo.Find(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := repo.First(ctx, "name = ?", "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound from First, got %v", err)
+	}
+	if err := repo.Create(ctx, nil); err == nil {
+		t.Error("Create should fail for nil model")
+	}
+}
@@ -0,0 +1,194 @@
+package activerecord
+
+import (
+	"strings"
+	"testing"
+)
+
+// returningSQLite exercises the RETURNING path on SQLite, which supports
+// INSERT ... RETURNING since 3.35.
+type returningSQLite struct{ SQLiteDialect }
+
+func (d returningSQLite) Returning(columns []string) string {
+	return "RETURNING " + strings.Join(quoteIdentifiers(d, columns), ", ")
+}
+
+type ticket struct {
+	BaseModel
+	Title  string `db:"title"`
+	Status string `db:"status,default"`
+}
+
+func (t *ticket) TableName() string { return "tickets" }
+
+func setupReturningTestDB(t *testing.T) {
+	RegisterDialect("sqlite3-returning", returningSQLite{})
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3-returning")
+	t.Cleanup(func() { SetConnection(db, "sqlite3") })
+
+	if _, err := db.Exec(`CREATE TABLE tickets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT,
+		status TEXT DEFAULT 'open',
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+}
+
+func TestCreateReturning(t *testing.T) {
+	setupReturningTestDB(t)
+
+	tk := &ticket{Title: "First"}
+	if err := Create(tk); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if tk.GetID() != int64(1) {
+		t.Errorf("expected ID 1, got %#v", tk.GetID())
+	}
+	if tk.Status != "open" {
+		t.Errorf("expected default status to be returned, got %q", tk.Status)
+	}
+
+	closed := &ticket{Title: "Second", Status: "closed"}
+	if err := Create(closed); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if closed.GetID() != int64(2) || closed.Status != "closed" {
+		t.Errorf("unexpected record: %+v", closed)
+	}
+}
+
+func TestBatchInsertReturning(t *testing.T) {
+	setupReturningTestDB(t)
+
+	tickets := []interface{}{&ticket{Title: "A"}, &ticket{Title: "B"}}
+	result, err := BatchInsert(tickets)
+	if err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+	if len(result.Errors) > 0 || result.RowsAffected != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for i, m := range tickets {
+		tk := m.(*ticket)
+		if tk.GetID() != int64(i+1) || tk.Status != "open" {
+			t.Errorf("ticket %d not populated: %+v", i, tk)
+		}
+	}
+}
+
+func TestBatchInsertColumnSets(t *testing.T) {
+	setupReturningTestDB(t)
+	SetConnection(GetConnection(), "sqlite3")
+
+	preset := &ticket{Title: "C"}
+	preset.SetID(int64(10))
+	tickets := []interface{}{&ticket{Title: "A"}, &ticket{Title: "B", Status: "closed"}, preset, &ticket{Title: "D"}}
+	result, err := BatchInsert(tickets)
+	if err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+	if len(result.Errors) > 0 || result.RowsAffected != 4 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for i, want := range []int64{1, 2, 10, 11} {
+		if id := tickets[i].(*ticket).GetID(); id != want {
+			t.Errorf("ticket %d has id %d, want %d", i, id, want)
+		}
+	}
+
+	var statuses []string
+	rows, err := GetConnection().Query("SELECT status FROM tickets ORDER BY id")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		statuses = append(statuses, status)
+	}
+	if strings.Join(statuses, ",") != "open,closed,open,open" {
+		t.Errorf("expected omitted defaults per row, got %v", statuses)
+	}
+}
+
+type upsertTag struct {
+	BaseModel
+	Name string `db:"name"`
+	Uses int    `db:"uses"`
+}
+
+func (t *upsertTag) TableName() string { return "upsert_tags" }
+
+func TestBatchUpsertKeys(t *testing.T) {
+	setupReturningTestDB(t)
+	if _, err := GetConnection().Exec(`CREATE TABLE upsert_tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE,
+		uses INTEGER,
+		created_at DATETIME,
+		updated_at DATETIME
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	ids := func(models []interface{}) []interface{} {
+		var got []interface{}
+		for _, m := range models {
+			got = append(got, m.(*upsertTag).GetID())
+		}
+		return got
+	}
+	idOf := func(name string) interface{} {
+		var id int64
+		if err := GetConnection().QueryRow("SELECT id FROM upsert_tags WHERE name = ?", name).Scan(&id); err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		return id
+	}
+	for _, dialect := range []string{"sqlite3", "sqlite3-returning"} {
+		SetConnection(GetConnection(), dialect)
+		if _, err := GetConnection().Exec("DELETE FROM upsert_tags"); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		if _, err := BatchInsert([]interface{}{&upsertTag{Name: "a"}, &upsertTag{Name: "b"}}); err != nil {
+			t.Fatalf("%s: BatchInsert failed: %v", dialect, err)
+		}
+
+		// an updated row gets its own key, not the previous row's
+		updated := []interface{}{&upsertTag{Name: "c"}, &upsertTag{Name: "a", Uses: 5}}
+		result, err := BatchUpsert(updated, []string{"name"}, []string{"uses"})
+		if err != nil || len(result.Errors) > 0 || result.RowsAffected != 2 {
+			t.Fatalf("%s: BatchUpsert failed: %+v, %v", dialect, result, err)
+		}
+		if got := ids(updated); got[0] != idOf("c") || got[1] != idOf("a") {
+			t.Errorf("%s: unexpected upserted ids %v", dialect, got)
+		}
+
+		// a row skipped by DO NOTHING keeps a blank key
+		skipped := []interface{}{&upsertTag{Name: "b"}, &upsertTag{Name: "d"}}
+		result, err = BatchUpsert(skipped, []string{"name"}, nil)
+		if err != nil || len(result.Errors) > 0 || result.RowsAffected != 1 {
+			t.Fatalf("%s: BatchUpsert failed: %+v, %v", dialect, result, err)
+		}
+		if got := ids(skipped); got[0] != nil || got[1] != idOf("d") {
+			t.Errorf("%s: unexpected ids after DO NOTHING %v", dialect, got)
+		}
+	}
+}
+
+func TestPostgresReturning(t *testing.T) {
+	got := (PostgresDialect{}).Returning([]string{"id", "created_at"})
+	if got != `RETURNING "id", "created_at"` {
+		t.Errorf("unexpected RETURNING clause: %s", got)
+	}
+}
@@ -0,0 +1,423 @@
+package activerecord
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	timePtrType = reflect.PtrTo(timeType)
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// FieldSchema describes a struct field mapped to a column.
+type FieldSchema struct {
+	Name    string
+	Column  string
+	Type    reflect.Type
+	Index   []int
+	Options map[string]string
+}
+
+// HasOption reports whether the field's db tag carries the given option.
+func (f *FieldSchema) HasOption(name string) bool {
+	_, ok := f.Options[name]
+	return ok
+}
+
+// RelationField describes a struct field holding related models.
+type RelationField struct {
+	Name  string
+	Type  reflect.Type
+	Elem  reflect.Type
+	Index []int
+	Many  bool
+}
+
+// ModelSchema is the parsed, cached mapping of a model type to its columns.
+type ModelSchema struct {
+	Type       reflect.Type
+	Fields     []*FieldSchema
+	PrimaryKey *FieldSchema
+	// PrimaryKeys holds every key column; it has more than one entry for
+	// composite keys, whose first column is also PrimaryKey.
+	PrimaryKeys []*FieldSchema
+	CreatedAt   *FieldSchema
+	UpdatedAt   *FieldSchema
+	// LockVersion is the optimistic locking column: the field tagged `lock`,
+	// or an integer lock_version column.
+	LockVersion *FieldSchema
+	// DeletedAt is the soft delete column: a *time.Time field tagged
+	// `soft_delete`, or the deleted_at column.
+	DeletedAt *FieldSchema
+	Relations []*RelationField
+
+	byColumn map[string]*FieldSchema
+	byName   map[string]*FieldSchema
+}
+
+// FieldByColumn returns the field mapped to a column.
+func (s *ModelSchema) FieldByColumn(column string) (*FieldSchema, bool) {
+	f, ok := s.byColumn[column]
+	return f, ok
+}
+
+// FieldByName returns the field with the given Go name.
+func (s *ModelSchema) FieldByName(name string) (*FieldSchema, bool) {
+	f, ok := s.byName[name]
+	return f, ok
+}
+
+// Relation returns the relation field with the given Go name.
+func (s *ModelSchema) Relation(name string) (*RelationField, bool) {
+	for _, r := range s.Relations {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+var schemaCache sync.Map // map[reflect.Type]*ModelSchema
+
+// SchemaOf returns the cached schema of a model, parsing it on first use.
+func SchemaOf(model interface{}) (*ModelSchema, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a struct or a pointer to a struct, got %T", model)
+	}
+	return schemaFor(t), nil
+}
+
+// schemaFor returns the cached schema of a struct type.
+func schemaFor(t reflect.Type) *ModelSchema {
+	if s, ok := schemaCache.Load(t); ok {
+		return s.(*ModelSchema)
+	}
+	s, _ := schemaCache.LoadOrStore(t, parseSchema(t))
+	return s.(*ModelSchema)
+}
+
+// parseSchema walks a struct type, including embedded structs, once.
+func parseSchema(t reflect.Type) *ModelSchema {
+	s := &ModelSchema{
+		Type:     t,
+		byColumn: make(map[string]*FieldSchema),
+		byName:   make(map[string]*FieldSchema),
+	}
+	s.parseFields(t, nil)
+
+	// Explicit `pk` tags or PrimaryKeyer/CompositeKeyer override the id
+	// convention.
+	var keys []*FieldSchema
+	for _, f := range s.Fields {
+		if f.HasOption("pk") {
+			keys = append(keys, f)
+		}
+	}
+	switch pker := reflect.New(t).Interface().(type) {
+	case CompositeKeyer:
+		keys = s.fieldsByColumn(pker.PrimaryKeys())
+	case PrimaryKeyer:
+		keys = s.fieldsByColumn([]string{pker.PrimaryKey()})
+	}
+	if len(keys) > 0 {
+		s.PrimaryKey = keys[0]
+		s.PrimaryKeys = keys
+	} else if s.PrimaryKey != nil {
+		s.PrimaryKeys = []*FieldSchema{s.PrimaryKey}
+	}
+
+	for _, f := range s.Fields {
+		if f.HasOption("lock") && isIntegerField(f) {
+			s.LockVersion = f
+			break
+		}
+	}
+	if f, ok := s.byColumn["lock_version"]; ok && s.LockVersion == nil && isIntegerField(f) {
+		s.LockVersion = f
+	}
+
+	for _, f := range s.Fields {
+		if f.HasOption("soft_delete") && f.Type == timePtrType {
+			s.DeletedAt = f
+			break
+		}
+	}
+	if f, ok := s.byColumn["deleted_at"]; ok && s.DeletedAt == nil && f.Type == timePtrType {
+		s.DeletedAt = f
+	}
+	return s
+}
+
+// fieldsByColumn returns the fields mapped to the given columns, or nil if
+// any of them is missing.
+func (s *ModelSchema) fieldsByColumn(columns []string) []*FieldSchema {
+	fields := make([]*FieldSchema, 0, len(columns))
+	for _, column := range columns {
+		f, ok := s.byColumn[column]
+		if !ok {
+			return nil
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// isKey reports whether f is part of the primary key.
+func (s *ModelSchema) isKey(f *FieldSchema) bool {
+	for _, k := range s.PrimaryKeys {
+		if k == f {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyColumns returns the primary key columns.
+func (s *ModelSchema) KeyColumns() []string {
+	columns := make([]string, len(s.PrimaryKeys))
+	for i, f := range s.PrimaryKeys {
+		columns[i] = f.Column
+	}
+	return columns
+}
+
+func (s *ModelSchema) parseFields(t reflect.Type, parent []int) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		ft := sf.Type
+		if sf.Anonymous {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				s.parseFields(ft, index)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag, hasTag := sf.Tag.Lookup("db")
+		column, options := parseTag(tag)
+		if !hasTag || column == "" || column == "-" {
+			if rel := relationOf(sf, index); rel != nil {
+				s.Relations = append(s.Relations, rel)
+				continue
+			}
+		}
+		if column == "-" {
+			continue
+		}
+		if column == "" {
+			column = strings.ToLower(sf.Name)
+		}
+		field := &FieldSchema{
+			Name:    sf.Name,
+			Column:  column,
+			Type:    sf.Type,
+			Index:   index,
+			Options: options,
+		}
+		if existing, dup := s.byColumn[column]; dup {
+			// Shallower fields shadow embedded ones, as with Go field promotion.
+			if len(existing.Index) <= len(index) {
+				continue
+			}
+			s.replaceField(existing, field)
+		} else {
+			s.Fields = append(s.Fields, field)
+		}
+		s.byColumn[column] = field
+		s.byName[field.Name] = field
+
+		switch {
+		case s.PrimaryKey == nil && (column == "id" || field.Name == "ID"):
+			s.PrimaryKey = field
+		case s.CreatedAt == nil && isTimeField(field) && (column == "created_at" || field.Name == "CreatedAt"):
+			s.CreatedAt = field
+		case s.UpdatedAt == nil && isTimeField(field) && (column == "updated_at" || field.Name == "UpdatedAt"):
+			s.UpdatedAt = field
+		}
+	}
+}
+
+// replaceField swaps a shadowed field for the one shadowing it.
+func (s *ModelSchema) replaceField(old, field *FieldSchema) {
+	for i, f := range s.Fields {
+		if f == old {
+			s.Fields[i] = field
+		}
+	}
+	if s.byName[old.Name] == old {
+		delete(s.byName, old.Name)
+	}
+	for _, ref := range []**FieldSchema{&s.PrimaryKey, &s.CreatedAt, &s.UpdatedAt} {
+		if *ref == old {
+			*ref = nil
+		}
+	}
+}
+
+// parseTag splits a db tag into the column name and its options.
+func parseTag(tag string) (string, map[string]string) {
+	parts := strings.Split(tag, ",")
+	options := make(map[string]string, len(parts)-1)
+	for _, opt := range parts[1:] {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(opt, "=")
+		options[key] = value
+	}
+	return strings.TrimSpace(parts[0]), options
+}
+
+// relationOf reports untagged or db:"-" fields that hold related models.
+func relationOf(sf reflect.StructField, index []int) *RelationField {
+	t := sf.Type
+	many := false
+	if t.Kind() == reflect.Slice {
+		many = true
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	} else if !many {
+		return nil
+	}
+	if t.Kind() != reflect.Struct || t == timeType ||
+		reflect.PtrTo(t).Implements(scannerType) || t.Implements(valuerType) {
+		return nil
+	}
+	return &RelationField{Name: sf.Name, Type: sf.Type, Elem: t, Index: index, Many: many}
+}
+
+func isTimeField(f *FieldSchema) bool {
+	return f.Type == timeType
+}
+
+func isIntegerField(f *FieldSchema) bool {
+	switch f.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// fieldByIndex returns the field at index, walking through embedded pointers.
+// Nil embedded pointers are allocated when alloc is set; otherwise the
+// returned bool is false.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// columnValues returns the columns and values to write for a model value.
+// Zero created_at/updated_at values are skipped so database defaults apply.
+func (s *ModelSchema) columnValues(v reflect.Value, excludeID bool) ([]string, []interface{}) {
+	fields := make([]string, 0, len(s.Fields))
+	values := make([]interface{}, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		if excludeID && s.isKey(f) {
+			continue
+		}
+		fv, ok := fieldByIndex(v, f.Index, false)
+		if !ok {
+			continue
+		}
+		if (f == s.CreatedAt || f == s.UpdatedAt) && fv.Interface().(time.Time).IsZero() {
+			continue
+		}
+		fields = append(fields, f.Column)
+		values = append(values, fv.Interface())
+	}
+	return fields, values
+}
+
+// insertValues returns the columns and values for an INSERT. An unset primary
+// key and unset columns tagged `default` are left for the database to fill.
+func (s *ModelSchema) insertValues(v reflect.Value) ([]string, []interface{}) {
+	fields := make([]string, 0, len(s.Fields))
+	values := make([]interface{}, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		fv, ok := fieldByIndex(v, f.Index, false)
+		if !ok {
+			continue
+		}
+		// Only a single-column key can be left for the database to generate.
+		generated := f == s.PrimaryKey && len(s.PrimaryKeys) == 1
+		if (generated || f.HasOption("default")) && fv.IsZero() {
+			continue
+		}
+		fields = append(fields, f.Column)
+		values = append(values, fv.Interface())
+	}
+	return fields, values
+}
+
+// valuesFor returns the values of the given columns for a model value.
+func (s *ModelSchema) valuesFor(v reflect.Value, columns []string) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		f, ok := s.byColumn[column]
+		if !ok {
+			continue
+		}
+		if fv, ok := fieldByIndex(v, f.Index, false); ok {
+			values[i] = fv.Interface()
+		}
+	}
+	return values
+}
+
+// returningColumns returns the columns an INSERT reads back: the primary key
+// and the columns tagged `default`.
+func (s *ModelSchema) returningColumns() []string {
+	columns := s.KeyColumns()
+	for _, f := range s.Fields {
+		if !s.isKey(f) && f.HasOption("default") {
+			columns = append(columns, f.Column)
+		}
+	}
+	return columns
+}
+
+// setTimestamps sets the created_at and updated_at fields to now.
+func (s *ModelSchema) setTimestamps(v reflect.Value, now time.Time) {
+	for _, f := range []*FieldSchema{s.CreatedAt, s.UpdatedAt} {
+		if f == nil {
+			continue
+		}
+		if fv, ok := fieldByIndex(v, f.Index, true); ok && fv.CanSet() {
+			fv.Set(reflect.ValueOf(now))
+		}
+	}
+}
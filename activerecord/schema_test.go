@@ -0,0 +1,200 @@
+package activerecord
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type SchemaAudit struct {
+	CreatedBy string `db:"created_by"`
+	Name      string `db:"audit_name"`
+}
+
+type schemaPost struct {
+	BaseModel
+	Title string `db:"title"`
+}
+
+type schemaTestModel struct {
+	BaseModel
+	*SchemaAudit
+	Name     string       `db:"name,size=100"`
+	Email    string       `db:"email"`
+	Nickname string       // untagged, defaults to lowercased field name
+	Secret   string       `db:"-"`
+	Author   *schemaPost  // belongs to
+	Posts    []schemaPost `db:"-"`
+	private  string
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema, err := SchemaOf(&schemaTestModel{})
+	if err != nil {
+		t.Fatalf("SchemaOf failed: %v", err)
+	}
+
+	var columns []string
+	for _, f := range schema.Fields {
+		columns = append(columns, f.Column)
+	}
+	want := []string{"id", "created_at", "updated_at", "created_by", "audit_name", "name", "email", "nickname"}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("unexpected columns:\n got: %v\nwant: %v", columns, want)
+	}
+
+	if schema.PrimaryKey == nil || schema.PrimaryKey.Column != "id" {
+		t.Errorf("unexpected primary key: %+v", schema.PrimaryKey)
+	}
+	if schema.CreatedAt == nil || schema.UpdatedAt == nil {
+		t.Error("timestamp fields not detected")
+	}
+
+	name, ok := schema.FieldByColumn("name")
+	if !ok || name.Options["size"] != "100" {
+		t.Errorf("tag options not parsed: %+v", name)
+	}
+	if _, ok := schema.FieldByName("Secret"); ok {
+		t.Error("db:\"-\" field should be skipped")
+	}
+
+	if len(schema.Relations) != 2 {
+		t.Fatalf("expected 2 relations, got %d", len(schema.Relations))
+	}
+	if rel, ok := schema.Relation("Author"); !ok || rel.Many || rel.Elem != reflect.TypeOf(schemaPost{}) {
+		t.Errorf("unexpected Author relation: %+v", rel)
+	}
+	if rel, ok := schema.Relation("Posts"); !ok || !rel.Many {
+		t.Errorf("unexpected Posts relation: %+v", rel)
+	}
+
+	again, _ := SchemaOf(schemaTestModel{})
+	if again != schema {
+		t.Error("schema should be cached per type")
+	}
+
+	if _, err := SchemaOf(42); err == nil {
+		t.Error("SchemaOf should fail for non-struct")
+	}
+}
+
+func TestSchemaShadowedField(t *testing.T) {
+	type inner struct {
+		Name string `db:"name"`
+	}
+	type outer struct {
+		inner
+		Name string `db:"name"`
+	}
+
+	schema, _ := SchemaOf(&outer{})
+	if len(schema.Fields) != 1 {
+		t.Fatalf("expected 1 field, got %d", len(schema.Fields))
+	}
+	if f := schema.Fields[0]; len(f.Index) != 1 {
+		t.Errorf("outer field should shadow embedded one, got index %v", f.Index)
+	}
+}
+
+func TestSchemaEmbeddedPointer(t *testing.T) {
+	m := &schemaTestModel{Name: "John"}
+
+	fields, values := getFieldsAndValues(m, true)
+	for _, f := range fields {
+		if f == "created_by" || f == "id" {
+			t.Errorf("unexpected field %s", f)
+		}
+	}
+	if len(fields) != len(values) {
+		t.Fatalf("fields and values length mismatch")
+	}
+
+	f, _ := schemaFor(reflect.TypeOf(*m)).FieldByColumn("created_by")
+	field, ok := fieldByIndex(reflect.ValueOf(m).Elem(), f.Index, true)
+	if !ok {
+		t.Fatal("fieldByIndex should allocate nil embedded pointer")
+	}
+	field.SetString("admin")
+	if m.SchemaAudit == nil || m.CreatedBy != "admin" {
+		t.Error("embedded pointer field not set")
+	}
+}
+
+func TestScanRowsValueSlice(t *testing.T) {
+	setupRepoTestDB(t)
+	for i := 0; i < 3; i++ {
+		if err := Create(&ModelTest{Name: fmt.Sprintf("User %d", i), Age: 20 + i}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var models []ModelTest
+	if err := FindAll(&models); err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(models) != 3 || models[2].Name != "User 2" || models[2].Age != 22 {
+		t.Errorf("unexpected models: %+v", models)
+	}
+}
+
+// BenchmarkSchemaParse measures the per-call reflection cost paid before
+// schemas were cached.
+func BenchmarkSchemaParse(b *testing.B) {
+	typ := reflect.TypeOf(schemaTestModel{})
+	for i := 0; i < b.N; i++ {
+		parseSchema(typ)
+	}
+}
+
+func BenchmarkSchemaCached(b *testing.B) {
+	typ := reflect.TypeOf(schemaTestModel{})
+	for i := 0; i < b.N; i++ {
+		schemaFor(typ)
+	}
+}
+
+func BenchmarkGetFieldsAndValues(b *testing.B) {
+	m := &ModelTest{Name: "John", Age: 30, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	for i := 0; i < b.N; i++ {
+		getFieldsAndValues(m, true)
+	}
+}
+
+func BenchmarkScanRows(b *testing.B) {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	SetConnection(db, "sqlite3")
+
+	if _, err := db.Exec(`CREATE TABLE model_tests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT,
+		age INTEGER,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	)`); err != nil {
+		b.Fatalf("Failed to create table: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if _, err := db.Exec("INSERT INTO model_tests (name, age) VALUES (?, ?)", fmt.Sprintf("User %d", i), i); err != nil {
+			b.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT id, name, age FROM model_tests")
+		if err != nil {
+			b.Fatalf("Query failed: %v", err)
+		}
+		var models []*ModelTest
+		if err := scanRows(rows, &models); err != nil {
+			b.Fatalf("scanRows failed: %v", err)
+		}
+		rows.Close()
+	}
+}
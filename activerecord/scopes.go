@@ -0,0 +1,101 @@
+package activerecord
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Scope is a reusable query fragment
+//
+//	func Active(qb *QueryBuilder) *QueryBuilder {
+//		return qb.Where("active = ?", true).WhereNull("banned_at")
+//	}
+type Scope func(*QueryBuilder) *QueryBuilder
+
+// modelScopes holds the scopes registered for a table.
+type modelScopes struct {
+	named        map[string]Scope
+	defaultScope Scope
+}
+
+var (
+	scopesMu sync.RWMutex
+	// scopes maps table names to their scopes, for query builders that only
+	// know their table.
+	scopes = make(map[string]*modelScopes)
+)
+
+func scopesFor(table string) *modelScopes {
+	s, ok := scopes[table]
+	if !ok {
+		s = &modelScopes{named: make(map[string]Scope)}
+		scopes[table] = s
+	}
+	return s
+}
+
+// RegisterScope registers a named scope for the model's table, applied with
+// QueryBuilder.Scope
+func RegisterScope(model Modeler, name string, scope Scope) {
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+	scopesFor(model.TableName()).named[name] = scope
+}
+
+// SetDefaultScope sets a scope applied to every query of the model's table,
+// including Find, FindAll, Where and association loading. Unscoped queries
+// skip it, as they skip soft deletes. A nil scope removes it.
+func SetDefaultScope(model Modeler, scope Scope) {
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+	scopesFor(model.TableName()).defaultScope = scope
+}
+
+func defaultScopeFor(table string) Scope {
+	scopesMu.RLock()
+	defer scopesMu.RUnlock()
+	if s, ok := scopes[table]; ok {
+		return s.defaultScope
+	}
+	return nil
+}
+
+// Scopes applies scopes to the query
+//
+//	qb.Scopes(Active, Recent)
+func (qb *QueryBuilder) Scopes(scopes ...Scope) *QueryBuilder {
+	for _, scope := range scopes {
+		qb = scope(qb)
+	}
+	return qb
+}
+
+// Scope applies the named scope registered for the query's table
+func (qb *QueryBuilder) Scope(name string) *QueryBuilder {
+	scopesMu.RLock()
+	var scope Scope
+	if s, ok := scopes[qb.tableName]; ok {
+		scope = s.named[name]
+	}
+	scopesMu.RUnlock()
+
+	if scope == nil {
+		return qb.fail(fmt.Errorf("unknown scope %q for table %s", name, qb.tableName))
+	}
+	return scope(qb)
+}
+
+// withDefaultScope returns a copy of the query with the default scope of
+// its table applied, or the query itself when it is unscoped or has none.
+func (qb *QueryBuilder) withDefaultScope() *QueryBuilder {
+	if qb.defaultScoped || qb.unscoped || isUnscoped(qb.ctx) {
+		return qb
+	}
+	scope := defaultScopeFor(qb.tableName)
+	if scope == nil {
+		return qb
+	}
+	q := qb.clone()
+	q.defaultScoped = true
+	return scope(q)
+}
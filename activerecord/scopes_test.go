@@ -0,0 +1,102 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func popular(qb *QueryBuilder) *QueryBuilder { return qb.Where("views >= ?", 20) }
+
+func byTitle(qb *QueryBuilder) *QueryBuilder { return qb.OrderBy("title", "asc") }
+
+func TestScopes(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"c", "a", "b", "d"} {
+		if err := Create(&article{Title: title, Views: i * 10}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var articles []*article
+	if err := NewQueryBuilder("articles").Scopes(popular, byTitle).Find(&articles); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(articles), []string{"b", "d"}) {
+		t.Errorf("unexpected scoped articles: %v", articleTitles(articles))
+	}
+
+	RegisterScope(&article{}, "popular", popular)
+	if count, err := NewQueryBuilder("articles").Scope("popular").Count(); err != nil || count != 2 {
+		t.Errorf("unexpected named scope count: %d, %v", count, err)
+	}
+	if _, err := NewQueryBuilder("articles").Scope("missing").Count(); err == nil {
+		t.Errorf("expected an error for an unknown scope")
+	}
+
+	// Named scopes resolve inside condition groups, and their errors surface
+	count, err := NewQueryBuilder("articles").WhereGroup(func(g *QueryBuilder) {
+		g.Scope("popular").OrWhere("title = ?", "a")
+	}).Count()
+	if err != nil || count != 3 {
+		t.Errorf("unexpected grouped scope count: %d, %v", count, err)
+	}
+	_, err = NewQueryBuilder("articles").WhereGroup(func(g *QueryBuilder) {
+		g.Scope("missing")
+	}).Count()
+	if err == nil {
+		t.Errorf("expected an error for an unknown scope in a group")
+	}
+}
+
+func TestDefaultScope(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"c", "a", "b", "d"} {
+		if err := Create(&article{Title: title, Body: "public", Views: i * 10}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	hidden := &article{Title: "e", Body: "hidden"}
+	if err := Create(hidden); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	SetDefaultScope(&article{}, func(qb *QueryBuilder) *QueryBuilder {
+		return qb.Where("body <> ?", "hidden").OrderBy("title", "desc")
+	})
+	defer SetDefaultScope(&article{}, nil)
+
+	var all []*article
+	if err := FindAll(&all); err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if !reflect.DeepEqual(articleTitles(all), []string{"d", "c", "b", "a"}) {
+		t.Errorf("unexpected FindAll result: %v", articleTitles(all))
+	}
+
+	var some []*article
+	if err := Where(&some, "views > ?", 0); err != nil || len(some) != 3 {
+		t.Errorf("unexpected Where result: %v, %v", articleTitles(some), err)
+	}
+
+	var found article
+	if err := Find(&found, hidden.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a record outside the default scope, got %v", err)
+	}
+	if err := FindWithContext(Unscoped(context.Background()), &found, hidden.ID); err != nil {
+		t.Errorf("unscoped Find failed: %v", err)
+	}
+
+	// Aggregates drop the default ordering
+	if count, err := NewQueryBuilder("articles").Count(); err != nil || count != 4 {
+		t.Errorf("unexpected scoped count: %d, %v", count, err)
+	}
+	if count, err := NewQueryBuilder("articles").Unscoped().Count(); err != nil || count != 5 {
+		t.Errorf("unexpected unscoped count: %d, %v", count, err)
+	}
+
+	if n, err := NewQueryBuilder("articles").UpdateColumn("views", 1); err != nil || n != 4 {
+		t.Errorf("expected UpdateAll to respect the default scope, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,195 @@
+package activerecord
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SoftDeletable is embedded by models whose Delete sets deleted_at instead
+// of removing the row. Soft-deleted rows are excluded from Find, FindAll,
+// Where, query builders and association loading unless queried Unscoped.
+type SoftDeletable struct {
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// IsDeleted reports whether the record has been soft-deleted.
+func (s *SoftDeletable) IsDeleted() bool {
+	return s.DeletedAt != nil
+}
+
+var (
+	modelTablesMu sync.RWMutex
+	// modelTables maps table names to the model types registered for them,
+	// for query builders that only know their table.
+	modelTables = make(map[string]reflect.Type)
+)
+
+// RegisterModel registers a model for its table, so that query builders
+// that only know the table, such as NewQueryBuilder("notes").Count(),
+// exclude its soft-deleted rows. Builders scanning into a model, or given
+// one with QueryBuilder.Model, need no registration.
+func RegisterModel(model Modeler) {
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	modelTablesMu.Lock()
+	defer modelTablesMu.Unlock()
+	modelTables[model.TableName()] = t
+}
+
+// registeredModel returns the model type registered for a table, or nil.
+func registeredModel(table string) reflect.Type {
+	modelTablesMu.RLock()
+	defer modelTablesMu.RUnlock()
+	return modelTables[table]
+}
+
+type unscopedKey struct{}
+
+// Unscoped returns a context under which queries include soft-deleted rows.
+//
+//	FindWithContext(Unscoped(ctx), &post, id)
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unscopedKey{}, true)
+}
+
+func isUnscoped(ctx context.Context) bool {
+	unscoped, _ := ctx.Value(unscopedKey{}).(bool)
+	return unscoped
+}
+
+// notDeleted returns the condition excluding soft-deleted rows of a table,
+// or "" when the schema has no deleted_at column or ctx is unscoped.
+func notDeleted(ctx context.Context, d Dialect, table string, schema *ModelSchema) string {
+	if schema == nil || schema.DeletedAt == nil || isUnscoped(ctx) {
+		return ""
+	}
+	column := schema.DeletedAt.Column
+	if isPlainIdentifier(table) {
+		column = table + "." + column
+	}
+	return quoteIdentifier(d, column) + " IS NULL"
+}
+
+// andNotDeleted appends the soft delete condition of a model to a WHERE
+// condition.
+func andNotDeleted(ctx context.Context, d Dialect, modeler Modeler, condition string) string {
+	schema := schemaFor(reflect.Indirect(reflect.ValueOf(modeler)).Type())
+	scope := notDeleted(ctx, d, modeler.TableName(), schema)
+	switch {
+	case scope == "":
+		return condition
+	case condition == "":
+		return scope
+	}
+	return "(" + condition + ") AND " + scope
+}
+
+// modelStructType returns the struct type of a model, a pointer to one or a
+// slice of either.
+func modelStructType(models interface{}) reflect.Type {
+	t := reflect.TypeOf(models)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// HardDelete deletes a record from the database, even if it is soft-deletable
+func HardDelete(model interface{}) error {
+	return HardDeleteWithContext(context.Background(), model)
+}
+
+// HardDeleteWithContext deletes a record from the database with context, even
+// if it is soft-deletable
+func HardDeleteWithContext(ctx context.Context, model interface{}) error {
+	modeler, ok := model.(Modeler)
+	if !ok {
+		return ErrNotModeler
+	}
+
+	d := GetDialect()
+	lock := lockFor(model)
+	keyColumns, keyValues := primaryKey(modeler)
+	where, args := lock.where(d, whereKey(d, keyColumns), keyValues)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s",
+		quoteTable(d, modeler.TableName()), where)
+	result, err := ExecWithContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete record: %w", err)
+	}
+	if inDryRun(ctx) {
+		return nil
+	}
+
+	return lock.check(modeler.TableName(), keyValues, result)
+}
+
+// Restore clears deleted_at of a soft-deleted record
+func Restore(model interface{}) error {
+	return RestoreWithContext(context.Background(), model)
+}
+
+// RestoreWithContext clears deleted_at of a soft-deleted record with context
+func RestoreWithContext(ctx context.Context, model interface{}) error {
+	return setDeletedAt(ctx, model, nil)
+}
+
+// setDeletedAt writes the deleted_at column of a record and the model.
+func setDeletedAt(ctx context.Context, model interface{}, deletedAt *time.Time) error {
+	exec := func(query string, args ...interface{}) (sql.Result, error) {
+		return ExecWithContext(ctx, query, args...)
+	}
+	return setDeletedAtOn(ctx, GetDialect(), exec, model, deletedAt)
+}
+
+// setDeletedAtOn sets deleted_at with a statement of dialect d run by exec.
+func setDeletedAtOn(ctx context.Context, d Dialect, exec func(string, ...interface{}) (sql.Result, error),
+	model interface{}, deletedAt *time.Time) error {
+	modeler, ok := model.(Modeler)
+	if !ok {
+		return ErrNotModeler
+	}
+	val := reflect.Indirect(reflect.ValueOf(model))
+	schema := schemaFor(val.Type())
+	if schema.DeletedAt == nil {
+		return fmt.Errorf("model %s is not soft-deletable", val.Type().Name())
+	}
+	field, _ := fieldByIndex(val, schema.DeletedAt.Index, true)
+
+	lock := lockFor(model)
+	fields, values := lock.set([]string{schema.DeletedAt.Column}, []interface{}{deletedAt})
+	setClause := make([]string, len(fields))
+	for i, f := range fields {
+		setClause[i] = fmt.Sprintf("%s = ?", quoteIdentifier(d, f))
+	}
+
+	keyColumns, keyValues := primaryKey(modeler)
+	where, args := lock.where(d, whereKey(d, keyColumns), keyValues)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		quoteTable(d, modeler.TableName()), strings.Join(setClause, ", "), where)
+	result, err := exec(query, append(values, args...)...)
+	if err != nil {
+		if deletedAt == nil {
+			return fmt.Errorf("failed to restore record: %w", err)
+		}
+		return fmt.Errorf("failed to delete record: %w", err)
+	}
+	if inDryRun(ctx) {
+		return nil
+	}
+	if err := lock.check(modeler.TableName(), keyValues, result); err != nil {
+		return err
+	}
+	lock.advance()
+
+	field.Set(reflect.ValueOf(deletedAt))
+	snapshotModel(model)
+	return nil
+}
@@ -0,0 +1,198 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+type note struct {
+	ActiveRecordModel
+	SoftDeletable
+	Title string `db:"title"`
+}
+
+func (n *note) TableName() string { return "notes" }
+
+func setupSoftDeleteTestDB(t *testing.T) []*note {
+	db, err := Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	SetConnection(db, "sqlite3")
+	if _, err := db.Exec(`CREATE TABLE notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT,
+		deleted_at TIMESTAMP NULL,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	var notes []*note
+	for _, title := range []string{"Keep", "Trash"} {
+		n := &note{Title: title}
+		if err := Create(n); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes
+}
+
+func TestSoftDelete(t *testing.T) {
+	notes := setupSoftDeleteTestDB(t)
+	trash := notes[1]
+
+	if err := trash.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if !trash.IsDeleted() || trash.Changed() {
+		t.Errorf("expected a clean, deleted model: %+v", trash)
+	}
+
+	if err := Find(&note{}, trash.GetID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	var all []*note
+	if err := FindAll(&all); err != nil || len(all) != 1 {
+		t.Errorf("FindAll should skip deleted rows: %d, %v", len(all), err)
+	}
+	var matched []*note
+	if err := Where(&matched, "title = ? OR title = ?", "Keep", "Trash"); err != nil || len(matched) != 1 {
+		t.Errorf("Where should skip deleted rows: %d, %v", len(matched), err)
+	}
+	if count, err := NewQueryBuilder("notes").Model(&note{}).Count(); err != nil || count != 1 {
+		t.Errorf("Count should skip deleted rows: %d, %v", count, err)
+	}
+	// a builder knowing only its table scopes the rows of a registered model
+	if count, err := NewQueryBuilder("notes").Count(); err != nil || count != 2 {
+		t.Errorf("expected Count of an unregistered table to include deleted rows: %d, %v", count, err)
+	}
+	RegisterModel(&note{})
+	if count, err := NewQueryBuilder("notes").Count(); err != nil || count != 1 {
+		t.Errorf("Count of a registered model should skip deleted rows: %d, %v", count, err)
+	}
+	batches := 0
+	err := FindInBatches(note{}, 10, func(batch []interface{}) error {
+		batches += len(batch)
+		return nil
+	})
+	if err != nil || batches != 1 {
+		t.Errorf("FindInBatches should skip deleted rows: %d, %v", batches, err)
+	}
+
+	// Unscoped queries include deleted rows
+	found := &note{}
+	if err := FindWithContext(Unscoped(context.Background()), found, trash.GetID()); err != nil || !found.IsDeleted() {
+		t.Errorf("expected deleted row unscoped: %+v, %v", found, err)
+	}
+	var everything []*note
+	if err := NewQueryBuilder("notes").Unscoped().Find(&everything); err != nil || len(everything) != 2 {
+		t.Errorf("expected 2 rows unscoped: %d, %v", len(everything), err)
+	}
+
+	if err := found.Restore(); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if found.IsDeleted() {
+		t.Error("restored model should not be deleted")
+	}
+	if err := Find(&note{}, trash.GetID()); err != nil {
+		t.Errorf("restored row should be found: %v", err)
+	}
+
+	if err := found.HardDelete(); err != nil {
+		t.Fatalf("HardDelete failed: %v", err)
+	}
+	if err := FindWithContext(Unscoped(context.Background()), &note{}, trash.GetID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("hard-deleted row should be gone, got %v", err)
+	}
+}
+
+func TestSoftDeleteWithConditions(t *testing.T) {
+	setupSoftDeleteTestDB(t)
+
+	affected, err := DeleteWithConditions(note{}, map[string]interface{}{"title": "Trash"})
+	if err != nil || affected != 1 {
+		t.Fatalf("DeleteWithConditions failed: %d, %v", affected, err)
+	}
+	// Already deleted rows are left alone
+	if affected, _ := DeleteWithConditions(note{}, map[string]interface{}{"title": "Trash"}); affected != 0 {
+		t.Errorf("expected no rows affected, got %d", affected)
+	}
+
+	var remaining []*note
+	if err := FindAll(&remaining); err != nil || len(remaining) != 1 || remaining[0].Title != "Keep" {
+		t.Errorf("unexpected remaining rows: %+v, %v", remaining, err)
+	}
+	var deleted []*note
+	if err := WhereWithContext(Unscoped(context.Background()), &deleted, "deleted_at IS NOT NULL"); err != nil || len(deleted) != 1 {
+		t.Errorf("expected the row to be soft-deleted: %d, %v", len(deleted), err)
+	}
+}
+
+func TestFindOrCreateSkipsDeleted(t *testing.T) {
+	notes := setupSoftDeleteTestDB(t)
+	if err := notes[1].Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	created := &note{}
+	if err := FindOrCreate(created, map[string]interface{}{"title": "Trash"}); err != nil {
+		t.Fatalf("FindOrCreate failed: %v", err)
+	}
+	if created.GetID() == notes[1].GetID() || created.IsDeleted() {
+		t.Errorf("expected a new record instead of the deleted one: %+v", created)
+	}
+
+	found := &note{}
+	if err := FindOrCreate(found, map[string]interface{}{"title": "Keep"}); err != nil {
+		t.Fatalf("FindOrCreate failed: %v", err)
+	}
+	if found.GetID() != notes[0].GetID() || found.Changed() {
+		t.Errorf("expected the existing record, clean: %+v", found)
+	}
+}
+
+func TestSoftDeleteOnDatabase(t *testing.T) {
+	dm := NewDatabaseManager()
+	resolver := NewDatabaseResolver()
+	dsn := filepath.Join(t.TempDir(), "notes.db")
+	if err := resolver.SetPrimary(&DatabaseConfig{Driver: "sqlite3", DSN: dsn, MaxOpen: 1}); err != nil {
+		t.Fatalf("SetPrimary failed: %v", err)
+	}
+	dm.AddDatabase("notes", resolver)
+	SetDatabaseManager(dm)
+	defer SetDatabaseManager(nil)
+	defer dm.Close()
+
+	if _, err := ExecOnDatabase("notes", Primary, `CREATE TABLE notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT,
+		deleted_at TIMESTAMP NULL,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	n := &note{Title: "Trash"}
+	if err := CreateOnDatabase("notes", n); err != nil {
+		t.Fatalf("CreateOnDatabase failed: %v", err)
+	}
+	if err := DeleteOnDatabase("notes", n); err != nil {
+		t.Fatalf("DeleteOnDatabase failed: %v", err)
+	}
+	if !n.IsDeleted() {
+		t.Error("expected the model to be soft-deleted")
+	}
+	if err := FindOnDatabase("notes", &note{}, n.GetID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a soft-deleted row, got %v", err)
+	}
+	var rows int
+	if err := QueryRowOnDatabase("notes", Primary, "SELECT COUNT(*) FROM notes").Scan(&rows); err != nil || rows != 1 {
+		t.Errorf("expected the row to be kept, got %d, %v", rows, err)
+	}
+}
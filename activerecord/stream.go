@@ -0,0 +1,54 @@
+package activerecord
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Each executes the query and calls fn with each row scanned into a new *T,
+// without loading the whole result set. It stops at the first error from fn
+// or when the query's context is cancelled, and always closes the rows.
+// Rows are passed in query order, which is reversed for Before.
+//
+//	err := Each(NewQueryBuilder("users").Where("active = ?", true), func(u *User) error {
+//		return export(u)
+//	})
+func Each[T any](qb *QueryBuilder, fn func(*T) error) error {
+	structType := reflect.TypeOf((*T)(nil)).Elem()
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot stream rows into %s: not a struct", structType)
+	}
+	if qb.model == nil {
+		qb.model = structType
+	}
+
+	rows, err := qb.Execute()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+	scanner := newRowScanner(schemaFor(structType), columns)
+	track := reflect.PtrTo(structType).Implements(dirtyTrackerType)
+
+	for rows.Next() {
+		if err := qb.ctx.Err(); err != nil {
+			return err
+		}
+		item := new(T)
+		if err := scanner.scan(rows, reflect.ValueOf(item).Elem()); err != nil {
+			return err
+		}
+		if track {
+			snapshotModel(item)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,35 @@
+//go:build go1.23
+
+package activerecord
+
+import (
+	"errors"
+	"iter"
+)
+
+// errStopIteration ends Each when the consumer of Seq breaks out of its loop.
+var errStopIteration = errors.New("stop iteration")
+
+// Seq returns an iterator over the rows of the query scanned into new *T
+// values, with the same guarantees as Each. An error is yielded once, with
+// a nil row, and ends the iteration.
+//
+//	for user, err := range Seq[User](NewQueryBuilder("users")) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Seq[T any](qb *QueryBuilder) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := Each(qb, func(item *T) error {
+			if !yield(item, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+//go:build go1.23
+
+package activerecord
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeq(t *testing.T) {
+	setupDirtyTestDB(t)
+	for _, title := range []string{"a", "b", "c"} {
+		if err := Create(&article{Title: title}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var titles []string
+	for a, err := range Seq[article](NewQueryBuilder("articles").OrderBy("title", "asc")) {
+		if err != nil {
+			t.Fatalf("Seq failed: %v", err)
+		}
+		titles = append(titles, a.Title)
+		if len(titles) == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(titles, []string{"a", "b"}) {
+		t.Errorf("unexpected rows: %v", titles)
+	}
+
+	for _, err := range Seq[article](NewQueryBuilder("missing_table")) {
+		if err == nil {
+			t.Errorf("expected an error for a missing table")
+		}
+	}
+
+	if count, err := NewQueryBuilder("articles").Count(); err != nil || count != 3 {
+		t.Errorf("unexpected count: %d, %v", count, err)
+	}
+}
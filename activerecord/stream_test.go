@@ -0,0 +1,55 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEach(t *testing.T) {
+	setupDirtyTestDB(t)
+	for _, title := range []string{"a", "b", "c"} {
+		if err := Create(&article{Title: title}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var titles []string
+	err := Each(NewQueryBuilder("articles").OrderBy("title", "desc"), func(a *article) error {
+		if a.Changed() {
+			t.Errorf("expected a clean snapshot for %s", a.Title)
+		}
+		titles = append(titles, a.Title)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(titles, []string{"c", "b", "a"}) {
+		t.Errorf("unexpected rows: %v, %v", titles, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = Each(NewQueryBuilder("articles"), func(a *article) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected callback error after 1 call, got %v after %d", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = Each(NewQueryBuilder("articles").WithContext(ctx), func(a *article) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("expected cancellation after 1 call, got %v after %d", err, calls)
+	}
+
+	// The connection must be free again, which it is not if rows leaked
+	if count, err := NewQueryBuilder("articles").Count(); err != nil || count != 3 {
+		t.Errorf("unexpected count: %d, %v", count, err)
+	}
+}
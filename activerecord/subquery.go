@@ -0,0 +1,219 @@
+package activerecord
+
+import (
+	"strings"
+)
+
+// cte is a common table expression added by With.
+type cte struct {
+	name  string
+	query *QueryBuilder
+}
+
+// compound is a query combined with UNION, INTERSECT or EXCEPT.
+type compound struct {
+	op    string
+	query *QueryBuilder
+}
+
+// With adds a common table expression, usable as a table by name
+//
+//	recent := NewQueryBuilder("orders").Where("created_at > ?", since)
+//	NewQueryBuilder("recent").With("recent", recent).Find(&orders)
+func (qb *QueryBuilder) With(name string, query *QueryBuilder) *QueryBuilder {
+	qb.ctes = append(qb.ctes, cte{name: name, query: query})
+	return qb
+}
+
+// WithRecursive adds a recursive common table expression. The name may list
+// its columns, e.g. "tree(id, parent_id)".
+func (qb *QueryBuilder) WithRecursive(name string, query *QueryBuilder) *QueryBuilder {
+	qb.recursive = true
+	return qb.With(name, query)
+}
+
+// withClause renders the WITH clause.
+func (qb *QueryBuilder) withClause(d Dialect) (string, []interface{}) {
+	parts := make([]string, len(qb.ctes))
+	var args []interface{}
+	for i, c := range qb.ctes {
+		sql, cteArgs := c.query.buildWith(d)
+		parts[i] = quoteIdentifier(d, c.name) + " AS (" + sql + ")"
+		args = append(args, cteArgs...)
+	}
+	keyword := "WITH "
+	if qb.recursive {
+		keyword = "WITH RECURSIVE "
+	}
+	return keyword + strings.Join(parts, ", "), args
+}
+
+// Union combines the results with another query, removing duplicates. ORDER
+// BY, LIMIT and OFFSET of the receiver apply to the combined result; those
+// of the other query apply to its own results only.
+func (qb *QueryBuilder) Union(query *QueryBuilder) *QueryBuilder {
+	return qb.combine("UNION", query)
+}
+
+// UnionAll combines the results with another query, keeping duplicates
+func (qb *QueryBuilder) UnionAll(query *QueryBuilder) *QueryBuilder {
+	return qb.combine("UNION ALL", query)
+}
+
+// Intersect keeps the results also returned by another query
+func (qb *QueryBuilder) Intersect(query *QueryBuilder) *QueryBuilder {
+	return qb.combine("INTERSECT", query)
+}
+
+// Except removes the results returned by another query
+func (qb *QueryBuilder) Except(query *QueryBuilder) *QueryBuilder {
+	return qb.combine("EXCEPT", query)
+}
+
+func (qb *QueryBuilder) combine(op string, query *QueryBuilder) *QueryBuilder {
+	qb.compounds = append(qb.compounds, compound{op: op, query: query})
+	return qb
+}
+
+// operandSQL renders the query of a compound. A query with its own ORDER BY,
+// LIMIT, OFFSET, WITH or compounds is wrapped in a derived table, where
+// those clauses are valid and apply to the query alone.
+func (c compound) operandSQL(d Dialect, alias string) (string, []interface{}) {
+	sql, args := c.query.buildWith(d)
+	q := c.query.withDefaultScope()
+	if len(q.orderBy) > 0 || len(q.keysetOrder()) > 0 || q.limit > 0 || q.offset > 0 ||
+		len(q.ctes) > 0 || len(q.compounds) > 0 {
+		sql = "SELECT * FROM (" + sql + ") AS " + quoteIdentifier(d, alias)
+	}
+	return sql, args
+}
+
+// SelectQuery adds a scalar subquery to the selected fields
+func (qb *QueryBuilder) SelectQuery(query *QueryBuilder, alias string) *QueryBuilder {
+	qb.selectFields = append(qb.selectFields, subqueryExpr{query: query, alias: alias})
+	return qb
+}
+
+// JoinQuery adds a join with a subquery as derived table
+func (qb *QueryBuilder) JoinQuery(query *QueryBuilder, alias, condition string) *QueryBuilder {
+	qb.joins = append(qb.joins, Raw("JOIN ? ON "+condition, subqueryExpr{query: query, alias: alias}))
+	return qb
+}
+
+// LeftJoinQuery adds a left join with a subquery as derived table
+func (qb *QueryBuilder) LeftJoinQuery(query *QueryBuilder, alias, condition string) *QueryBuilder {
+	qb.joins = append(qb.joins, Raw("LEFT JOIN ? ON "+condition, subqueryExpr{query: query, alias: alias}))
+	return qb
+}
+
+// subqueryExpr is a parenthesized query, optionally aliased.
+type subqueryExpr struct {
+	query *QueryBuilder
+	alias string
+}
+
+func (e subqueryExpr) SQL(d Dialect) (string, []interface{}) {
+	sql, args := e.query.buildWith(d)
+	sql = "(" + sql + ")"
+	if e.alias != "" {
+		sql += " AS " + quoteIdentifier(d, e.alias)
+	}
+	return sql, args
+}
+
+// nestedQueries returns the queries used by qb as CTEs, compound operands
+// and subqueries.
+func (qb *QueryBuilder) nestedQueries() []*QueryBuilder {
+	var queries []*QueryBuilder
+	for _, c := range qb.ctes {
+		queries = append(queries, c.query)
+	}
+	for _, c := range qb.compounds {
+		queries = append(queries, c.query)
+	}
+	for _, exprs := range [][]Expr{qb.selectFields, qb.joins, qb.where, qb.groupBy, qb.having, qb.orderBy} {
+		for _, e := range exprs {
+			queries = appendSubqueries(queries, e)
+		}
+	}
+	return queries
+}
+
+// appendSubqueries appends the subqueries of an expression to queries.
+func appendSubqueries(queries []*QueryBuilder, v interface{}) []*QueryBuilder {
+	switch e := v.(type) {
+	case *QueryBuilder:
+		return append(queries, e)
+	case subqueryExpr:
+		return append(queries, e.query)
+	case rawExpr:
+		for _, arg := range e.args {
+			queries = appendSubqueries(queries, arg)
+		}
+	case compareExpr:
+		return appendSubqueries(queries, e.value)
+	case inExpr:
+		if len(e.values) == 1 {
+			return appendSubqueries(queries, e.values[0])
+		}
+	case junction:
+		for _, expr := range e.exprs {
+			queries = appendSubqueries(queries, expr)
+		}
+	case notExpr:
+		return appendSubqueries(queries, e.expr)
+	}
+	return queries
+}
+
+// asSubquery returns v as an inlinable subquery.
+func asSubquery(v interface{}) (Expr, bool) {
+	switch v := v.(type) {
+	case *QueryBuilder:
+		return subqueryExpr{query: v}, true
+	case subqueryExpr:
+		return v, true
+	}
+	return nil, false
+}
+
+// inlineSubqueries replaces the placeholders of subquery args with the
+// subqueries' SQL and their args.
+func inlineSubqueries(d Dialect, sql string, args []interface{}) (string, []interface{}) {
+	found := false
+	for _, arg := range args {
+		if _, ok := asSubquery(arg); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return sql, args
+	}
+
+	var out strings.Builder
+	var outArgs []interface{}
+	n := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(sql, i, c)
+			out.WriteString(sql[i:end])
+			i = end - 1
+		case c == '?' && n < len(args):
+			if sub, ok := asSubquery(args[n]); ok {
+				subSQL, subArgs := sub.SQL(d)
+				out.WriteString(subSQL)
+				outArgs = append(outArgs, subArgs...)
+			} else {
+				out.WriteByte(c)
+				outArgs = append(outArgs, args[n])
+			}
+			n++
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), append(outArgs, args[n:]...)
+}
@@ -0,0 +1,118 @@
+package activerecord
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubqueryBuild(t *testing.T) {
+	pg := PostgresDialect{}
+	active := NewQueryBuilder("accounts").Select("user_id").Where("status = ?", "active")
+	totals := NewQueryBuilder("orders").Select("user_id", "SUM(total) AS total").
+		Where("total > ?", 10).GroupBy("user_id")
+	latest := NewQueryBuilder("logins").Select("MAX(at)").Where("logins.user_id = users.id")
+
+	qb := NewQueryBuilder("users").SetDialect(pg).
+		With("active", active).
+		SelectQuery(latest, "last_login").
+		JoinQuery(totals, "t", "t.user_id = users.id").
+		Where("age > ?", 18).
+		WhereIn("id", []interface{}{NewQueryBuilder("active").Select("user_id")}).
+		WhereExpr(Eq("team_id", NewQueryBuilder("teams").Select("id").Where("name = ?", "core")))
+
+	query, args := qb.Build()
+	want := `WITH "active" AS (SELECT "user_id" FROM "accounts" WHERE status = $1) ` +
+		`SELECT *, (SELECT MAX(at) FROM "logins" WHERE logins.user_id = users.id) AS "last_login" FROM "users" ` +
+		`JOIN (SELECT "user_id", SUM(total) AS total FROM "orders" WHERE total > $2 GROUP BY "user_id") AS "t" ON t.user_id = users.id ` +
+		`WHERE age > $3 AND id IN (SELECT "user_id" FROM "active") AND "team_id" = (SELECT "id" FROM "teams" WHERE name = $4)`
+	if query != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", query, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"active", 10, 18, "core"}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	union, args := NewQueryBuilder("admins").SetDialect(pg).Select("email").Where("active = ?", true).
+		UnionAll(NewQueryBuilder("users").Select("email").Where("age > ?", 18)).
+		Except(NewQueryBuilder("bounces").Select("email")).
+		OrderBy("email", "asc").Limit(5).Build()
+	want = `SELECT "email" FROM "admins" WHERE active = $1 UNION ALL SELECT "email" FROM "users" WHERE age > $2 ` +
+		`EXCEPT SELECT "email" FROM "bounces" ORDER BY "email" ASC LIMIT 5`
+	if union != want {
+		t.Errorf("unexpected union:\n got: %s\nwant: %s", union, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, 18}) {
+		t.Errorf("unexpected union args: %v", args)
+	}
+
+	top, _ := NewQueryBuilder("admins").SetDialect(pg).Select("email").
+		Union(NewQueryBuilder("users").Select("email").OrderBy("age", "desc").Limit(3)).
+		Except(NewQueryBuilder("bounces").Select("email").Union(NewQueryBuilder("complaints").Select("email"))).
+		Build()
+	want = `SELECT "email" FROM "admins" UNION SELECT * FROM (SELECT "email" FROM "users" ORDER BY "age" DESC LIMIT 3) AS "compound_1" ` +
+		`EXCEPT SELECT * FROM (SELECT "email" FROM "bounces" UNION SELECT "email" FROM "complaints") AS "compound_2"`
+	if top != want {
+		t.Errorf("unexpected compound operands:\n got: %s\nwant: %s", top, want)
+	}
+}
+
+func TestSubqueryFind(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"Go", "Rust", "Zig", "Go"} {
+		if err := Create(&article{Title: title, Views: i * 10}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	// Articles sharing a title with a popular one
+	popular := NewQueryBuilder("articles").Select("title").Where("views >= ?", 30)
+	var articles []*article
+	if err := NewQueryBuilder("articles").WhereExpr(In("title", popular)).Find(&articles); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(articles) != 2 {
+		t.Errorf("expected 2 articles, got %d", len(articles))
+	}
+
+	var recent []*article
+	err := NewQueryBuilder("recent").
+		With("recent", NewQueryBuilder("articles").Where("views > ?", 0)).
+		Where("title <> ?", "Zig").
+		Find(&recent)
+	if err != nil || len(recent) != 2 {
+		t.Errorf("unexpected CTE result: %d, %v", len(recent), err)
+	}
+
+	var titles []string
+	err = NewQueryBuilder("articles").Select("title").Where("views < ?", 15).
+		Union(NewQueryBuilder("articles").Select("title").Where("views > ?", 25)).
+		OrderBy("title", "asc").
+		Pluck("title", &titles)
+	if err != nil || !reflect.DeepEqual(titles, []string{"Go", "Rust"}) {
+		t.Errorf("unexpected union titles: %v, %v", titles, err)
+	}
+
+	titles = nil
+	err = NewQueryBuilder("articles").Select("title").Where("views < ?", 15).
+		Except(NewQueryBuilder("articles").Select("title").OrderBy("views", "desc").Limit(1)).
+		Pluck("title", &titles)
+	if err != nil || !reflect.DeepEqual(titles, []string{"Rust"}) {
+		t.Errorf("unexpected except titles: %v, %v", titles, err)
+	}
+
+	count, err := NewQueryBuilder("articles").Select("title").
+		UnionAll(NewQueryBuilder("articles").Select("title")).
+		Count()
+	if err != nil || count != 8 {
+		t.Errorf("unexpected union count: %d, %v", count, err)
+	}
+
+	var numbers []int64
+	err = NewQueryBuilder("n").Select("x").
+		WithRecursive("n(x)", NewQueryBuilder("articles").SelectExpr(Raw("1")).Where("title = ?", "Rust").
+			UnionAll(NewQueryBuilder("n").SelectExpr(Raw("x + 1")).Where("x < ?", 3))).
+		Pluck("x", &numbers)
+	if err != nil || !reflect.DeepEqual(numbers, []int64{1, 2, 3}) {
+		t.Errorf("unexpected recursive CTE result: %v, %v", numbers, err)
+	}
+}
@@ -244,17 +244,17 @@ func (t *Transaction) AddCallback(callback func() error) {
 
 // Exec executes a query within the transaction
 func (t *Transaction) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return t.tx.ExecContext(t.ctx, query, args...)
+	return t.tx.ExecContext(t.ctx, Rebind(GetDialect(), query), args...)
 }
 
 // Query executes a query and returns rows
 func (t *Transaction) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return t.tx.QueryContext(t.ctx, query, args...)
+	return t.tx.QueryContext(t.ctx, Rebind(GetDialect(), query), args...)
 }
 
 // QueryRow executes a query and returns a single row
 func (t *Transaction) QueryRow(query string, args ...interface{}) *sql.Row {
-	return t.tx.QueryRowContext(t.ctx, query, args...)
+	return t.tx.QueryRowContext(t.ctx, Rebind(GetDialect(), query), args...)
 }
 
 // IsCommitted returns true if the transaction is committed
@@ -0,0 +1,174 @@
+package activerecord
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UpdateAll updates the rows matched by the query in a single statement and
+// returns the number of rows affected. updates is either a map of column
+// values, where Expr and *QueryBuilder values are rendered as SQL, or an
+// Expr for the whole SET clause. Callbacks, timestamps and lock versions are
+// not applied.
+//
+//	n, err := NewQueryBuilder("orders").Where("status = ?", "pending").
+//		UpdateAll(map[string]interface{}{"status": "expired"})
+func (qb *QueryBuilder) UpdateAll(updates interface{}) (int64, error) {
+	d := qb.getDialect()
+	set, args, err := setClause(d, updates)
+	if err != nil {
+		return 0, err
+	}
+	query := "UPDATE " + quoteTable(d, qb.tableName) + " SET " + set
+	return qb.execMatching("update", query, args)
+}
+
+// UpdateColumn sets a column of the rows matched by the query
+func (qb *QueryBuilder) UpdateColumn(column string, value interface{}) (int64, error) {
+	return qb.UpdateAll(map[string]interface{}{column: value})
+}
+
+// Increment adds n to a column of the rows matched by the query
+func (qb *QueryBuilder) Increment(column string, n interface{}) (int64, error) {
+	return qb.adjust(column, "+", n)
+}
+
+// Decrement subtracts n from a column of the rows matched by the query
+func (qb *QueryBuilder) Decrement(column string, n interface{}) (int64, error) {
+	return qb.adjust(column, "-", n)
+}
+
+func (qb *QueryBuilder) adjust(column, op string, n interface{}) (int64, error) {
+	quoted := quoteIdentifier(qb.getDialect(), column)
+	return qb.UpdateAll(map[string]interface{}{column: Raw(quoted+" "+op+" ?", n)})
+}
+
+// DeleteAll deletes the rows matched by the query in a single statement and
+// returns the number of rows affected. Rows of soft-deletable models are
+// marked deleted instead. Callbacks are not run.
+func (qb *QueryBuilder) DeleteAll() (int64, error) {
+	if schema := qb.softDeleteSchema(); schema != nil && schema.DeletedAt != nil {
+		return qb.UpdateColumn(schema.DeletedAt.Column, time.Now())
+	}
+	return qb.HardDeleteAll()
+}
+
+// HardDeleteAll permanently deletes the rows matched by the query, including
+// those of soft-deletable models
+func (qb *QueryBuilder) HardDeleteAll() (int64, error) {
+	query := "DELETE FROM " + quoteTable(qb.getDialect(), qb.tableName)
+	return qb.execMatching("delete", query, nil)
+}
+
+// execMatching executes an UPDATE or DELETE statement restricted to the rows
+// matched by the query and returns the number of rows affected.
+func (qb *QueryBuilder) execMatching(verb, query string, args []interface{}) (int64, error) {
+	if err := qb.validate(); err != nil {
+		return 0, err
+	}
+	d := qb.getDialect()
+	where, whereArgs := qb.matchingCondition(d)
+	if where != "" {
+		query += " WHERE " + where
+		args = append(args, whereArgs...)
+	}
+	query = Rebind(d, query)
+
+	if c, ok := qb.dryRun(); ok {
+		c.record(d, query, args)
+		return 0, nil
+	}
+
+	result, err := GetConnection().ExecContext(qb.ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to %s records: %w", verb, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// matchingCondition returns the condition selecting the rows matched by the
+// query. Not every dialect supports joins, ordering or limits in UPDATE and
+// DELETE, so such queries match the keys they select through a derived
+// table, which also lets MySQL select from the table being changed.
+func (qb *QueryBuilder) matchingCondition(d Dialect) (string, []interface{}) {
+	qb = qb.withDefaultScope()
+	if len(qb.joins) == 0 && len(qb.groupBy) == 0 && len(qb.having) == 0 && len(qb.compounds) == 0 &&
+		len(qb.ctes) == 0 && !qb.distinct && qb.limit == 0 && qb.offset == 0 {
+		return qb.whereClause(d)
+	}
+
+	keys := qb.keyColumns()
+	selected := make([]Expr, len(keys))
+	for i, key := range keys {
+		selected[i] = Raw(quoteIdentifier(d, qb.tableName+"."+key))
+	}
+	matched := qb.clone()
+	matched.selectFields = selected
+	sql, args := matched.buildWith(d)
+
+	list := strings.Join(quoteIdentifiers(d, keys), ", ")
+	columns := list
+	if len(keys) > 1 {
+		columns = "(" + list + ")"
+	}
+	return fmt.Sprintf("%s IN (SELECT %s FROM (%s) AS %s)",
+		columns, list, sql, quoteIdentifier(d, "matched")), args
+}
+
+// keyColumns returns the primary key columns of the model, defaulting to id.
+func (qb *QueryBuilder) keyColumns() []string {
+	if qb.model == nil {
+		return []string{"id"}
+	}
+	return primaryKeyColumns(reflect.New(qb.model).Interface())
+}
+
+// setClause renders the SET clause of UpdateAll.
+func setClause(d Dialect, updates interface{}) (string, []interface{}, error) {
+	switch updates := updates.(type) {
+	case map[string]interface{}:
+		if len(updates) == 0 {
+			return "", nil, fmt.Errorf("no updates provided")
+		}
+		columns := make([]string, 0, len(updates))
+		for column := range updates {
+			if err := checkIdentifier(column); err != nil {
+				return "", nil, err
+			}
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		parts := make([]string, len(columns))
+		var args []interface{}
+		for i, column := range columns {
+			value := updates[column]
+			if sub, ok := asSubquery(value); ok {
+				value = sub
+			}
+			if expr, ok := value.(Expr); ok {
+				sql, exprArgs := expr.SQL(d)
+				parts[i] = quoteIdentifier(d, column) + " = " + sql
+				args = append(args, exprArgs...)
+				continue
+			}
+			parts[i] = quoteIdentifier(d, column) + " = ?"
+			args = append(args, value)
+		}
+		return strings.Join(parts, ", "), args, nil
+	case Expr:
+		sql, args := updates.SQL(d)
+		if sql == "" {
+			return "", nil, fmt.Errorf("no updates provided")
+		}
+		return sql, args, nil
+	}
+	return "", nil, fmt.Errorf("updates must be a map or an Expr, got %T", updates)
+}
@@ -0,0 +1,95 @@
+package activerecord
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdateAllMatchingCondition(t *testing.T) {
+	pg := PostgresDialect{}
+	qb := NewQueryBuilder("articles").
+		Join("users", "users.id = articles.user_id").
+		Where("users.name = ?", "ann").
+		OrderBy("views", "desc").Limit(2)
+
+	where, args := qb.matchingCondition(pg)
+	want := `"id" IN (SELECT "id" FROM (SELECT "articles"."id" FROM "articles" JOIN "users" ON users.id = articles.user_id ` +
+		`WHERE users.name = ? ORDER BY "views" DESC LIMIT 2) AS "matched")`
+	if where != want {
+		t.Errorf("unexpected condition:\n got: %s\nwant: %s", where, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"ann"}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestUpdateAll(t *testing.T) {
+	setupDirtyTestDB(t)
+	for i, title := range []string{"a", "b", "c", "d"} {
+		if err := Create(&article{Title: title, Views: i * 10}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	n, err := NewQueryBuilder("articles").Where("views >= ?", 20).
+		UpdateAll(map[string]interface{}{"body": "popular", "views": Raw("views * ?", 2)})
+	if err != nil || n != 2 {
+		t.Fatalf("unexpected UpdateAll result: %d, %v", n, err)
+	}
+	if sum, err := NewQueryBuilder("articles").Where("body = ?", "popular").Sum("views"); err != nil || sum != 100 {
+		t.Errorf("unexpected views after UpdateAll: %v, %v", sum, err)
+	}
+
+	// Ordered and limited updates go through the key subquery
+	if n, err := NewQueryBuilder("articles").OrderBy("views", "asc").Limit(2).UpdateColumn("body", "first"); err != nil || n != 2 {
+		t.Errorf("unexpected UpdateColumn result: %d, %v", n, err)
+	}
+	var titles []string
+	if err := NewQueryBuilder("articles").Where("body = ?", "first").OrderBy("title", "asc").Pluck("title", &titles); err != nil ||
+		!reflect.DeepEqual(titles, []string{"a", "b"}) {
+		t.Errorf("unexpected updated titles: %v, %v", titles, err)
+	}
+
+	if _, err := NewQueryBuilder("articles").Where("title = ?", "a").Increment("views", 5); err != nil {
+		t.Fatalf("Increment failed: %v", err)
+	}
+	if _, err := NewQueryBuilder("articles").Where("title = ?", "b").Decrement("views", 3); err != nil {
+		t.Fatalf("Decrement failed: %v", err)
+	}
+	var views []int64
+	if err := NewQueryBuilder("articles").Where("title IN (?, ?)", "a", "b").OrderBy("title", "asc").Pluck("views", &views); err != nil ||
+		!reflect.DeepEqual(views, []int64{5, 7}) {
+		t.Errorf("unexpected views: %v, %v", views, err)
+	}
+
+	if _, err := NewQueryBuilder("articles").UpdateAll(map[string]interface{}{}); err == nil {
+		t.Errorf("expected an error for empty updates")
+	}
+
+	n, err = NewQueryBuilder("articles").Where("views > ?", 6).OrderBy("views", "desc").Limit(1).DeleteAll()
+	if err != nil || n != 1 {
+		t.Fatalf("unexpected DeleteAll result: %d, %v", n, err)
+	}
+	if count, _ := NewQueryBuilder("articles").Count(); count != 3 {
+		t.Errorf("expected 3 articles after DeleteAll, got %d", count)
+	}
+}
+
+func TestDeleteAllSoftDelete(t *testing.T) {
+	setupSoftDeleteTestDB(t)
+
+	n, err := NewQueryBuilder("notes").Model(&note{}).Where("title = ?", "Trash").DeleteAll()
+	if err != nil || n != 1 {
+		t.Fatalf("unexpected DeleteAll result: %d, %v", n, err)
+	}
+	if count, _ := NewQueryBuilder("notes").Model(&note{}).Count(); count != 1 {
+		t.Errorf("expected 1 visible note, got %d", count)
+	}
+	if count, _ := NewQueryBuilder("notes").Unscoped().Count(); count != 2 {
+		t.Errorf("expected the note to be kept, got %d notes", count)
+	}
+
+	if n, err := NewQueryBuilder("notes").Unscoped().HardDeleteAll(); err != nil || n != 2 {
+		t.Errorf("unexpected HardDeleteAll result: %d, %v", n, err)
+	}
+}